@@ -0,0 +1,343 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"libdb.so/catnip-gtk4/internal/catnipctl"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// options are the command-line options. They override the saved config for
+// the session only.
+type options struct {
+	backend    string
+	device     string
+	sampleRate float64
+	drawStyle  *catnipgtk.DrawStyle
+	configFile string
+	profile    string
+	noSave     bool
+	fullscreen bool
+}
+
+// overrideOptions are the options that choose or override the config. They
+// are shared by the application and the headless modes.
+var overrideOptions = []struct {
+	name  string
+	arg   glib.OptionArg
+	usage string
+	value string // name of the value in the help
+}{
+	{"backend", glib.OptionArgString, "Input backend to use", "NAME"},
+	{"device", glib.OptionArgString, "Input device to use", "NAME"},
+	{"sample-rate", glib.OptionArgDouble, "Sample rate in Hz", "RATE"},
+	{"draw-style", glib.OptionArgString, "Draw style: bars, lines, circle, waterfall or oscilloscope", "STYLE"},
+	{"config", glib.OptionArgString, "Use this config file instead of config.json", "FILE"},
+	{"profile", glib.OptionArgString, "Use the config of this profile", "NAME"},
+}
+
+// addOptions adds the options to the application. GApplication forwards them
+// to the primary instance if it is already running.
+func addOptions(a *gtk.Application) {
+	for _, opt := range overrideOptions {
+		a.AddMainOption(opt.name, 0, glib.OptionFlagNone, opt.arg, opt.usage, opt.value)
+	}
+	a.AddMainOption("no-save", 0, glib.OptionFlagNone, glib.OptionArgNone,
+		"Do not save any changes to the config", "")
+	a.AddMainOption("fullscreen", 0, glib.OptionFlagNone, glib.OptionArgNone,
+		"Start in fullscreen", "")
+}
+
+// parseOptions parses the options of the given command line.
+func parseOptions(cmd *gio.ApplicationCommandLine) (options, error) {
+	dict := cmd.OptionsDict()
+
+	str := func(name string) string {
+		if v := dict.LookupValue(name, glib.NewVariantType("s")); v != nil {
+			return v.String()
+		}
+		return ""
+	}
+
+	var sampleRate float64
+	if v := dict.LookupValue("sample-rate", glib.NewVariantType("d")); v != nil {
+		sampleRate = v.Double()
+	}
+
+	opts := options{
+		noSave:     dict.Contains("no-save"),
+		fullscreen: dict.Contains("fullscreen"),
+	}
+
+	// The command line may come from another process, so resolve the path
+	// against its working directory instead of ours.
+	err := opts.parseOverrides(str, sampleRate, dict.Contains("sample-rate"), cmd.Cwd())
+	return opts, err
+}
+
+// addOverrideFlags adds overrideOptions to the flag set. The returned function
+// parses the flags into options once the flag set is parsed.
+func addOverrideFlags(flags *flag.FlagSet) func() (options, error) {
+	for _, opt := range overrideOptions {
+		usage := fmt.Sprintf("%s (`%s`)", opt.usage, opt.value)
+		switch opt.arg {
+		case glib.OptionArgString:
+			flags.String(opt.name, "", usage)
+		case glib.OptionArgDouble:
+			flags.Float64(opt.name, 0, usage)
+		}
+	}
+
+	return func() (options, error) {
+		set := make(map[string]bool)
+		flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+		str := func(name string) string {
+			return flags.Lookup(name).Value.String()
+		}
+
+		sampleRate := flags.Lookup("sample-rate").Value.(flag.Getter).Get().(float64)
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			return options{}, err
+		}
+
+		var opts options
+		err = opts.parseOverrides(str, sampleRate, set["sample-rate"], cwd)
+		return opts, err
+	}
+}
+
+// parseOverrides parses the values of overrideOptions. str returns the value
+// of a string option, or an empty string if it is not set. Relative config
+// paths are resolved against cwd.
+func (o *options) parseOverrides(str func(name string) string, sampleRate float64, hasSampleRate bool, cwd string) error {
+	o.backend = str("backend")
+	o.device = str("device")
+	o.configFile = str("config")
+	o.profile = str("profile")
+
+	if hasSampleRate {
+		if sampleRate <= 0 {
+			return fmt.Errorf("invalid --sample-rate %v", sampleRate)
+		}
+		o.sampleRate = sampleRate
+	}
+
+	if name := str("draw-style"); name != "" {
+		style, err := catnipgtk.ParseDrawStyle(name)
+		if err != nil {
+			return err
+		}
+		o.drawStyle = &style
+	}
+
+	if o.configFile != "" && o.profile != "" {
+		return fmt.Errorf("--config and --profile cannot be used together")
+	}
+
+	if o.configFile != "" && !filepath.IsAbs(o.configFile) {
+		o.configFile = filepath.Join(cwd, o.configFile)
+	}
+
+	return nil
+}
+
+// savePath returns the file that the config is loaded from and saved to, or
+// an empty string for config.json.
+func (o options) savePath() string {
+	switch {
+	case o.configFile != "":
+		return o.configFile
+	case o.profile != "":
+		return catnipgtk.ProfilePath(o.profile)
+	default:
+		return ""
+	}
+}
+
+// loadConfig loads the config from savePath without the overrides.
+func (o options) loadConfig() (catnipgtk.Config, error) {
+	path := o.savePath()
+	if path != "" {
+		return catnipgtk.RestoreConfigFile(path)
+	}
+
+	config, err := catnipgtk.RestoreConfig()
+	if err != nil {
+		log.Println("cannot restore config:", err)
+
+		backup := catnipgtk.ConfigFile + ".bak"
+		if config, err = catnipgtk.RestoreConfigFile(backup); err == nil {
+			log.Println("using backup config", backup)
+			return config, nil
+		}
+
+		log.Println("using default config")
+		config = catnipgtk.DefaultConfig()
+	}
+	return config, nil
+}
+
+// override applies the overrides to the config.
+func (o options) override(cfg *catnipgtk.Config) {
+	if o.backend != "" {
+		cfg.Backend = o.backend
+	}
+	if o.device != "" {
+		cfg.Device = o.device
+	}
+	if o.sampleRate > 0 {
+		cfg.SampleRate = o.sampleRate
+	}
+	if o.drawStyle != nil {
+		cfg.DrawStyle = *o.drawStyle
+	}
+}
+
+// revert undoes the overrides that are still in effect, so that they are not
+// saved. Fields that were changed since are kept.
+func (o options) revert(cfg *catnipgtk.Config, base catnipgtk.Config) {
+	if o.backend != "" && cfg.Backend == o.backend {
+		cfg.Backend = base.Backend
+	}
+	if o.device != "" && cfg.Device == o.device {
+		cfg.Device = base.Device
+	}
+	if o.sampleRate > 0 && cfg.SampleRate == o.sampleRate {
+		cfg.SampleRate = base.SampleRate
+	}
+	if o.drawStyle != nil && cfg.DrawStyle == *o.drawStyle {
+		cfg.DrawStyle = base.DrawStyle
+	}
+}
+
+// session is the state of the primary instance.
+type session struct {
+	instance *catnipctl.Instance
+	window   *gtk.Window
+	opts     options
+	base     catnipgtk.Config // config as loaded, without the overrides
+	saved    catnipgtk.Config // config last loaded or saved
+
+	watching string // path of the config file being monitored
+	monitor  *gio.FileMonitor
+	onReload func(error)
+	css      *userCSS
+}
+
+// newSession creates a session. onReload is called after the config file is
+// reloaded because it was changed on disk. It is called with an error if the
+// new file is invalid, in which case the config is only reloaded if the
+// invalid values could be repaired.
+func newSession(opts options, instance *catnipctl.Instance, window *gtk.Window, base catnipgtk.Config, onReload func(error)) *session {
+	s := &session{
+		instance: instance,
+		window:   window,
+		opts:     opts,
+		base:     base,
+		saved:    base,
+		onReload: onReload,
+	}
+	instance.SetSaveFunc(s.save)
+
+	// Switching profiles replaces the whole config, so the overrides and the
+	// config file no longer apply.
+	instance.ConnectProfileChanged(func(string) {
+		s.opts = options{noSave: s.opts.noSave}
+		s.base = *instance.Config()
+		s.saved = s.base
+		s.watchConfig()
+	})
+
+	s.watchConfig()
+	return s
+}
+
+// path returns the config file that the session loads and saves.
+func (s *session) path() string {
+	if s.opts.configFile != "" {
+		return s.opts.configFile
+	}
+	if profile := s.instance.Profile(); profile != "" {
+		return catnipgtk.ProfilePath(profile)
+	}
+	return catnipgtk.ConfigFile
+}
+
+func (s *session) save(cfg catnipgtk.Config, done func(error)) {
+	if s.opts.noSave {
+		if done != nil {
+			done(nil)
+		}
+		return
+	}
+
+	s.opts.revert(&cfg, s.base)
+	s.saved = cfg
+	// A renamed profile is saved under its new name.
+	s.watchConfig()
+
+	if s.opts.configFile == "" && s.instance.Profile() == "" {
+		cfg.SaveAsync(done)
+	} else {
+		cfg.SaveAsyncTo(s.path(), done)
+	}
+}
+
+// apply applies the options of another invocation to the running session.
+func (s *session) apply(opts options) error {
+	// Restart at most once for all the changes.
+	resume := s.instance.PauseUpdates()
+	defer resume()
+
+	switch {
+	case opts.profile != "":
+		if err := s.instance.SwitchProfile(opts.profile); err != nil {
+			return err
+		}
+	case opts.configFile != "":
+		base, err := opts.loadConfig()
+		if err != nil {
+			return err
+		}
+
+		s.base = base
+		s.saved = base
+		s.opts = options{configFile: opts.configFile, noSave: s.opts.noSave}
+		s.instance.SetProfile("")
+		s.instance.Update(func(cfg *catnipgtk.Config) { *cfg = base })
+		s.watchConfig()
+	}
+
+	s.opts.noSave = s.opts.noSave || opts.noSave
+	if opts.backend != "" {
+		s.opts.backend = opts.backend
+	}
+	if opts.device != "" {
+		s.opts.device = opts.device
+	}
+	if opts.sampleRate > 0 {
+		s.opts.sampleRate = opts.sampleRate
+	}
+	if opts.drawStyle != nil {
+		s.opts.drawStyle = opts.drawStyle
+	}
+
+	s.instance.Update(opts.override)
+
+	if opts.fullscreen {
+		s.window.Fullscreen()
+	}
+	s.window.Present()
+
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"libdb.so/catnip-gtk4/internal/audiofile"
+	"libdb.so/catnip-gtk4/internal/catnipctl"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// headlessFlags are the flags that switch catnip-gtk4 into running without a
+// window.
+var headlessFlags = []string{"render-frames", "export-video"}
+
+// wantsHeadless returns true if the arguments contain any of headlessFlags.
+func wantsHeadless(args []string) bool {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		for _, flag := range headlessFlags {
+			if name == flag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// runHeadless parses the headless flags and either renders frames until
+// interrupted or exports a video. The options that override the config work the
+// same way as with a window. It never initializes GTK.
+func runHeadless(args []string) {
+	flags := flag.NewFlagSet("catnip-gtk4", flag.ExitOnError)
+
+	var (
+		framesDir  = flags.String("render-frames", "", "render PNG frames into `DIR` without a window")
+		fps        = flags.Int("render-fps", 30, "number of frames to render per second")
+		width      = flags.Int("render-width", 600, "width of each frame")
+		height     = flags.Int("render-height", 350, "height of each frame")
+		duration   = flags.Duration("render-duration", 0, "stop rendering frames after this long (default until interrupted)")
+		background = flags.String("render-background", "#000000", "background color of each frame")
+		foreground = flags.String("render-foreground", "#ffffff", "color used in place of the theme color")
+
+		videoInput   = flags.String("export-video", "", "export a video of the WAV or FLAC audio in `FILE`")
+		videoOutput  = flags.String("export-output", "-", "write the video into `FILE`, or - for stdout")
+		videoFormat  = flags.String("export-format", string(catnipctl.VideoY4M), "video format, either y4m or rgba")
+		videoCommand = flags.String("export-command", "", "pipe the video into this shell `COMMAND` instead of a file")
+	)
+	parseOverrides := addOverrideFlags(flags)
+	flags.Parse(args)
+
+	opts, err := parseOverrides()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	bg, err := catnipgtk.ParseColor(*background)
+	if err != nil {
+		log.Fatalln("invalid --render-background:", err)
+	}
+	fg, err := catnipgtk.ParseColor(*foreground)
+	if err != nil {
+		log.Fatalln("invalid --render-foreground:", err)
+	}
+
+	config, err := opts.loadConfig()
+	if err != nil {
+		log.Fatalln("cannot load config:", err)
+	}
+	opts.override(&config)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if *videoInput != "" {
+		err = exportVideo(ctx, config, *videoInput, *videoOutput, *videoCommand, catnipctl.ExportOptions{
+			Format:     catnipctl.VideoFormat(*videoFormat),
+			FrameRate:  *fps,
+			Width:      *width,
+			Height:     *height,
+			Background: bg,
+			Foreground: fg,
+		})
+	} else {
+		err = catnipctl.RenderFrames(ctx, config, catnipctl.RenderOptions{
+			Dir:        *framesDir,
+			FrameRate:  float64(*fps),
+			Width:      *width,
+			Height:     *height,
+			Duration:   *duration,
+			Background: bg,
+			Foreground: fg,
+		})
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func exportVideo(ctx context.Context, config catnipgtk.Config, input, output, command string, opts catnipctl.ExportOptions) error {
+	f, err := audiofile.Open(input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if command == "" {
+		var w io.WriteCloser = os.Stdout
+		if output != "-" {
+			if w, err = os.Create(output); err != nil {
+				return err
+			}
+		}
+
+		if err := catnipctl.ExportVideo(ctx, config, f.WAV, w, opts); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	}
+
+	// Let the command know what it is getting, e.g.
+	// ffmpeg -f rawvideo -pix_fmt rgba -s $CATNIP_SIZE -r $CATNIP_FPS -i - out.mp4
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("CATNIP_SIZE=%dx%d", opts.Width, opts.Height),
+		fmt.Sprintf("CATNIP_FPS=%d", opts.FrameRate),
+	)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start export command: %w", err)
+	}
+
+	exportErr := catnipctl.ExportVideo(ctx, config, f.WAV, stdin, opts)
+	stdin.Close()
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("export command failed: %w", err)
+	}
+	return exportErr
+}
@@ -0,0 +1,103 @@
+package audiofile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// File is an opened audio file.
+type File struct {
+	*WAV
+	close func() error
+}
+
+// Open opens a WAV or FLAC file. FLAC files are decoded by the flac command,
+// so they can only be opened if it is installed.
+func Open(path string) (*File, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wav":
+		return openWAV(path)
+	case ".flac":
+		return openFLAC(path)
+	default:
+		return nil, fmt.Errorf("audiofile: unsupported file %q", filepath.Base(path))
+	}
+}
+
+// IsSupported returns true if Open can open the file at path, judging by its
+// extension.
+func IsSupported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".wav":
+		return true
+	case ".flac":
+		return canDecodeFLAC()
+	default:
+		return false
+	}
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	return f.close()
+}
+
+func openWAV(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("audiofile: %w", err)
+	}
+
+	wav, err := NewWAV(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &File{WAV: wav, close: f.Close}, nil
+}
+
+func canDecodeFLAC() bool {
+	path, _ := exec.LookPath("flac")
+	return path != ""
+}
+
+func openFLAC(path string) (*File, error) {
+	// flac writes the decoded audio as a WAV stream.
+	cmd := exec.Command("flac", "--decode", "--stdout", "--silent", path)
+	cmd.Stderr = os.Stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("audiofile: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("audiofile: failed to start flac: %w", err)
+	}
+
+	close := func() error {
+		stdout.Close()
+		// Closing stdout early kills flac with SIGPIPE, which is expected.
+		var exitErr *exec.ExitError
+		if err := cmd.Wait(); err != nil && !errors.As(err, &exitErr) {
+			return err
+		}
+		return nil
+	}
+
+	wav, err := NewWAV(stdout)
+	if err != nil {
+		close()
+		return nil, err
+	}
+
+	return &File{WAV: wav, close: close}, nil
+}
+
+var _ io.Closer = (*File)(nil)
@@ -0,0 +1,194 @@
+// Package audiofile decodes audio files into samples that catnip can use.
+package audiofile
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatFloat      = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// WAV decodes samples from a RIFF WAVE stream. Integer PCM of 8 to 32 bits and
+// 32 or 64-bit floats are supported.
+type WAV struct {
+	r          io.Reader
+	format     uint16
+	channels   int
+	sampleRate int
+	bitDepth   int
+	remaining  int64 // bytes left in the data chunk
+	buf        []byte
+}
+
+// NewWAV reads the header of the WAV stream in r. The reader is left at the
+// start of the samples.
+func NewWAV(r io.Reader) (*WAV, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, fmt.Errorf("audiofile: failed to read RIFF header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, errors.New("audiofile: not a WAV file")
+	}
+
+	w := &WAV{r: r}
+	var haveFormat bool
+
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, fmt.Errorf("audiofile: failed to find data chunk: %w", err)
+		}
+
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+
+		switch id {
+		case "fmt ":
+			if err := w.readFormat(size); err != nil {
+				return nil, err
+			}
+			haveFormat = true
+
+		case "data":
+			if !haveFormat {
+				return nil, errors.New("audiofile: data chunk before fmt chunk")
+			}
+			w.remaining = size
+			return w, nil
+
+		default:
+			// Chunks are padded to an even size.
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return nil, fmt.Errorf("audiofile: failed to skip %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+func (w *WAV) readFormat(size int64) error {
+	if size < 16 {
+		return fmt.Errorf("audiofile: fmt chunk too small (%d bytes)", size)
+	}
+
+	chunk := make([]byte, size+size%2)
+	if _, err := io.ReadFull(w.r, chunk); err != nil {
+		return fmt.Errorf("audiofile: failed to read fmt chunk: %w", err)
+	}
+
+	w.format = binary.LittleEndian.Uint16(chunk[0:2])
+	w.channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+	w.sampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+	w.bitDepth = int(binary.LittleEndian.Uint16(chunk[14:16]))
+
+	if w.format == wavFormatExtensible && size >= 26 {
+		// The actual format is the start of the sub-format GUID.
+		w.format = binary.LittleEndian.Uint16(chunk[24:26])
+	}
+
+	switch {
+	case w.channels < 1:
+		return errors.New("audiofile: WAV file has no channels")
+	case w.sampleRate < 1:
+		return fmt.Errorf("audiofile: invalid sample rate %d", w.sampleRate)
+	case w.format == wavFormatPCM && (w.bitDepth < 8 || w.bitDepth > 32 || w.bitDepth%8 != 0):
+		return fmt.Errorf("audiofile: unsupported PCM bit depth %d", w.bitDepth)
+	case w.format == wavFormatFloat && w.bitDepth != 32 && w.bitDepth != 64:
+		return fmt.Errorf("audiofile: unsupported float bit depth %d", w.bitDepth)
+	case w.format != wavFormatPCM && w.format != wavFormatFloat:
+		return fmt.Errorf("audiofile: unsupported WAV format 0x%04X", w.format)
+	}
+
+	return nil
+}
+
+// SampleRate returns the number of frames per second.
+func (w *WAV) SampleRate() float64 {
+	return float64(w.sampleRate)
+}
+
+// ChannelCount returns the number of channels in each frame.
+func (w *WAV) ChannelCount() int {
+	return w.channels
+}
+
+// Read reads up to len(dst[0]) frames into dst, which has a buffer for each
+// channel. Extra channels in the file are dropped, and channels missing from
+// the file are copied from the previous ones. The samples are in [-1, 1]. It
+// returns the number of frames read and io.EOF once there are none left.
+func (w *WAV) Read(dst [][]float64) (int, error) {
+	if len(dst) == 0 || len(dst[0]) == 0 {
+		return 0, nil
+	}
+
+	frameSize := w.channels * w.bitDepth / 8
+	frames := min(len(dst[0]), int(w.remaining/int64(frameSize)))
+	if frames == 0 {
+		return 0, io.EOF
+	}
+
+	if cap(w.buf) < frames*frameSize {
+		w.buf = make([]byte, frames*frameSize)
+	}
+	buf := w.buf[:frames*frameSize]
+
+	n, err := io.ReadFull(w.r, buf)
+	frames = n / frameSize
+	w.remaining -= int64(n)
+
+	sampleSize := w.bitDepth / 8
+	for i := 0; i < frames; i++ {
+		frame := buf[i*frameSize:]
+		for ch := range dst {
+			fileCh := ch % w.channels
+			dst[ch][i] = w.decode(frame[fileCh*sampleSize:])
+		}
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		// The data chunk was cut short. Treat it as the end of the file.
+		w.remaining = 0
+		err = nil
+	}
+	if frames == 0 && err == nil {
+		err = io.EOF
+	}
+
+	return frames, err
+}
+
+func (w *WAV) decode(b []byte) float64 {
+	if w.format == wavFormatFloat {
+		if w.bitDepth == 64 {
+			return math.Float64frombits(binary.LittleEndian.Uint64(b))
+		}
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	}
+
+	switch w.bitDepth {
+	case 8:
+		// 8-bit samples are unsigned.
+		return (float64(b[0]) - 128) / 128
+	case 16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case 24:
+		v := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8
+		return float64(v) / (1 << 23)
+	default:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	}
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
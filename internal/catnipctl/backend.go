@@ -0,0 +1,52 @@
+package catnipctl
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/noriah/catnip/input"
+)
+
+// backends counts the users of each catnip backend. Backends are global, so
+// a backend is only initialized by its first user and closed by its last,
+// instead of being closed under a run that is still using it.
+var backends = struct {
+	sync.Mutex
+	users map[string]int
+}{
+	users: make(map[string]int),
+}
+
+// acquireBackend returns the named backend, initializing it if nobody else is
+// using it. release must be called once the backend is no longer used.
+func acquireBackend(name string) (backend input.Backend, release func(), err error) {
+	backend = input.FindBackend(name)
+	if backend == nil {
+		return nil, nil, fmt.Errorf("backend not found: %q; check list-backends", name)
+	}
+
+	backends.Lock()
+	defer backends.Unlock()
+
+	if backends.users[name] == 0 {
+		if err := backend.Init(); err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize input backend: %w", err)
+		}
+	}
+	backends.users[name]++
+
+	var once sync.Once
+	release = func() {
+		once.Do(func() {
+			backends.Lock()
+			defer backends.Unlock()
+
+			if backends.users[name]--; backends.users[name] == 0 {
+				delete(backends.users, name)
+				backend.Close()
+			}
+		})
+	}
+
+	return backend, release, nil
+}
@@ -2,41 +2,99 @@ package catnipctl
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"sync"
 
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
-	"github.com/diamondburned/gotkit/app"
 	"github.com/noriah/catnip"
 	"github.com/noriah/catnip/dsp"
 	"libdb.so/catnip-gtk4/internal/catnipgtk"
+	"libdb.so/catnip-gtk4/internal/fileinput"
 )
 
 // Instance is a singleton instance of a catnip visualizer.
 // It makes it easier to start and stop the visualizer with settings.
+//
+// Its methods may be called from any goroutine. Only one catnip run is active
+// at a time: runs are started and stopped by a single goroutine owned by the
+// instance, which waits for a run to finish before starting the next one.
 type Instance struct {
-	config    catnipgtk.Config
 	display   catnipgtk.Display
 	parentCtx context.Context
+	kick      chan struct{} // wakes up loop
+	wg        sync.WaitGroup
 
-	wg      sync.WaitGroup
-	stop    context.CancelFunc
-	paused  int  // nested pause counter
-	changed bool // true if changed while paused
+	mu       sync.Mutex // guards the fields below
+	config   catnipgtk.Config
+	paused   int  // nested pause counter
+	changed  bool // true if changed while paused
+	want     bool // true if started and not stopped
+	hidden   bool // true if the display cannot be seen
+	restart  bool // true if the run should be restarted
+	quitting bool
+	state    State
+	lastErr  error
+
+	profile          string
+	onChanged        []func(catnipgtk.Config)
+	onProfileChanged []func(string)
+	onStateChanged   []func(State)
+	save             SaveFunc
 }
 
+// SaveFunc saves the given config asynchronously and calls done afterwards.
+// It is called on the main thread.
+type SaveFunc func(cfg catnipgtk.Config, done func(error))
+
 // NewInstance creates a new instance of the catnip visualizer.
 func NewInstance(ctx context.Context, config catnipgtk.Config, display catnipgtk.Display) *Instance {
-	return &Instance{
+	i := &Instance{
 		config:    config,
 		display:   display,
 		parentCtx: ctx,
+		kick:      make(chan struct{}, 1),
+		save:      catnipgtk.Config.SaveAsync,
 	}
+
+	i.wg.Add(1)
+	go i.loop()
+
+	return i
+}
+
+// SetSaveFunc sets the function used by Save. By default, the config is saved
+// to the config file. A nil function disables saving.
+func (i *Instance) SetSaveFunc(save SaveFunc) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.save = save
+}
+
+// Save saves the current config using the SaveFunc. done is called on the
+// main thread with the error if saving fails, or with nil once it is saved or
+// if saving is disabled.
+func (i *Instance) Save(done func(error)) {
+	i.mu.Lock()
+	save, cfg := i.save, i.config
+	i.mu.Unlock()
+
+	// Savers are only used from the main thread.
+	glib.IdleAdd(func() {
+		switch {
+		case save != nil:
+			save(cfg, done)
+		case done != nil:
+			done(nil)
+		}
+	})
 }
 
 // Config returns a copy of the current configuration.
 func (i *Instance) Config() *catnipgtk.Config {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
 	cfg := i.config
 	return &cfg
 }
@@ -50,22 +108,27 @@ func (i *Instance) Context() context.Context {
 // function that resumes the updates. If the config is changed while the
 // visualizer is paused, it will be restarted once it is resumed.
 func (i *Instance) PauseUpdates() (resume func()) {
+	i.mu.Lock()
 	old := i.config
 	i.paused++
+	i.mu.Unlock()
 
 	var once sync.Once
 	return func() {
 		once.Do(func() {
+			i.mu.Lock()
+			defer i.mu.Unlock()
+
 			i.paused--
 			if old != i.config {
 				// Mark as changed in case we're nested but can't apply the
 				// changes.
 				i.changed = true
 			}
-			if i.paused == 0 && i.stop != nil && i.changed {
+			if i.paused == 0 && i.want && i.changed {
 				// Only restart if we're not nested and we have changes.
 				i.changed = false
-				i.Start()
+				i.restartLocked()
 			}
 		})
 	}
@@ -73,33 +136,118 @@ func (i *Instance) PauseUpdates() (resume func()) {
 
 // UpdateIsPaused returns true if the Update function is paused.
 func (i *Instance) UpdateIsPaused() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
 	return i.paused != 0
 }
 
+// ConnectConfigChanged calls f with the new config every time the config is
+// changed using Update. f is called on the goroutine that called Update.
+func (i *Instance) ConnectConfigChanged(f func(cfg catnipgtk.Config)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.onChanged = append(i.onChanged, f)
+}
+
 // Update updates the catnip visualizer with the new settings and restarts it.
-// If the visualizer is not running, it will not be started.
+// If the visualizer is not running, it will not be started. f is called with
+// the instance locked, so it must not call any of its methods.
 func (i *Instance) Update(f func(cfg *catnipgtk.Config)) {
+	i.mu.Lock()
 	old := i.config
-	f(&i.config)
+	cfg := old
+	f(&cfg)
+	i.config = cfg
+
+	onChanged := i.onChanged
+	paused := i.paused != 0
+	onlyDisplay := catnipgtk.ConfigOnlyChangedDisplay(old, cfg)
+	if !paused && !onlyDisplay && i.want {
+		i.restartLocked()
+	}
+	if old.PauseWhenIdle != cfg.PauseWhenIdle && i.hidden {
+		i.wake()
+	}
+	i.mu.Unlock()
+
+	if old != cfg {
+		for _, f := range onChanged {
+			f(cfg)
+		}
+	}
 
-	if i.paused != 0 {
-		return
+	if !paused && onlyDisplay {
+		// The display belongs to the main thread, and Update may not be called
+		// from it.
+		glib.IdleAdd(func() { applyDisplayConfig(i.display, cfg) })
 	}
+}
 
-	if catnipgtk.ConfigOnlyChangedDisplay(old, i.config) {
-		i.display.SetSizes(i.config.LineWidth, i.config.GapWidth)
-		i.display.SetLineCap(i.config.LineCap)
-		i.display.SetDrawStyle(i.config.DrawStyle)
-		return
+// applyDisplayConfig applies the display-only parts of the config to the
+// visualizer.
+func applyDisplayConfig(v catnipgtk.Visualizer, c catnipgtk.Config) {
+	v.SetSizes(c.LineWidth, c.GapWidth)
+	v.SetLineCap(c.LineCap)
+	v.SetDrawStyle(c.DrawStyle)
+	v.SetWaterfallParams(c.WaterfallHistory, c.WaterfallDirection, c.ColorMap)
+	v.SetPeakCaps(c.PeakHoldDuration(), c.PeakFallRate, c.PeakCapThickness)
+	v.SetScaling(c.Scaling())
+	v.SetFrequencyMapping(c.FrequencyMapping().Clamp(c.SampleRate))
+	v.SetAxes(c.ShowAxes)
+	v.SetPauseWhenIdle(c.PauseWhenIdle)
+	v.SetFrameRate(c.FrameRate)
+	v.SetInterpolation(c.Interpolate)
+
+	left, right := c.ChannelGradients()
+	v.SetColors(c.ColorMode, left, right)
+
+	if display, ok := v.(catnipgtk.RendererDisplay); ok {
+		display.SetRenderer(c.Renderer)
 	}
+}
+
+func (i *Instance) convertConfig(ctx context.Context, c catnipgtk.Config) catnip.Config {
+	config := newCatnipConfig(c, i.display)
+	config.SetupFunc = func() error {
+		done := make(chan struct{})
+		glib.IdleAdd(func() {
+			i.display.SetSamplingParams(c.SampleRate, c.SampleSize)
+			applyDisplayConfig(i.display, c)
+			close(done)
+		})
+
+		// Don't wait for the main thread if it is waiting for us to stop,
+		// such as in Finalize.
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 
-	if i.stop != nil {
-		i.Start()
+		i.setStateIf(StateStarting, StateRunning)
+		return nil
 	}
+	return config
 }
 
-func (i *Instance) convertConfig(c catnipgtk.Config) catnip.Config {
-	output := i.display.AsOutput()
+// newCatnipConfig creates a catnip config that draws onto the given visualizer.
+// The visualizer's settings are not touched.
+func newCatnipConfig(c catnipgtk.Config, v catnipgtk.Visualizer) catnip.Config {
+	// catnip looks up the device before calling any of our functions, so the
+	// file backend has to be set up now.
+	fileinput.SetOptions(fileinput.Options{
+		Folder: c.FileFolder,
+		Loop:   c.FileLoop,
+	})
+
+	output := v.AsOutput()
+
+	analyzer := catnipgtk.NewAnalyzer(c.SampleRate, c.SampleSize, c.FrequencyMapping())
+	if c.MagnitudeScale == catnipgtk.MagnitudeDecibel {
+		analyzer.SetDecibelScale(c.DecibelScale())
+	}
 
 	return catnip.Config{
 		Backend:      c.Backend,
@@ -111,15 +259,6 @@ func (i *Instance) convertConfig(c catnipgtk.Config) catnip.Config {
 		Windower:     catnipgtk.WindowFuncs[c.WindowFunc],
 		Output:       output,
 		SetupFunc: func() error {
-			done := make(chan struct{})
-			glib.IdleAdd(func() {
-				i.display.SetSizes(c.LineWidth, c.GapWidth)
-				i.display.SetLineCap(c.LineCap)
-				i.display.SetDrawStyle(c.DrawStyle)
-				i.display.SetSamplingParams(c.SampleRate, c.SampleSize)
-				close(done)
-			})
-			<-done
 			return nil
 		},
 		StartFunc: func(ctx context.Context) (context.Context, error) {
@@ -130,12 +269,7 @@ func (i *Instance) convertConfig(c catnipgtk.Config) catnip.Config {
 			output.Discard()
 			return nil
 		},
-		Analyzer: dsp.NewAnalyzer(dsp.AnalyzerConfig{
-			SampleRate: c.SampleRate,
-			SampleSize: c.SampleSize,
-			SquashLow:  true,
-			BinMethod:  dsp.MaxSampleValue(),
-		}),
+		Analyzer: analyzer,
 		Smoother: dsp.NewSmoother(dsp.SmootherConfig{
 			SampleRate:      c.SampleRate,
 			SampleSize:      c.SampleSize,
@@ -146,38 +280,69 @@ func (i *Instance) convertConfig(c catnipgtk.Config) catnip.Config {
 	}
 }
 
-func (i *Instance) error(err error) {
-	app.Error(i.parentCtx, fmt.Errorf("catnip: %w", err))
+// sampleOutput returns the SampleOutput of the visualizer, or nil if it does
+// not want samples.
+func sampleOutput(v catnipgtk.Visualizer) catnipgtk.SampleOutput {
+	if display, ok := v.(catnipgtk.SampleDisplay); ok {
+		return display.AsSampleOutput()
+	}
+	return nil
 }
 
 // Finalize kills all instances and wait for them to finish.
 // No more methods should be called after this.
 func (i *Instance) Finalize() {
-	i.Stop()
+	i.mu.Lock()
+	i.quitting = true
+	i.mu.Unlock()
+
+	i.wake()
 	i.wg.Wait()
 }
 
 // Start starts the catnip visualizer. If it is already running, it will be
-// restarted.
+// restarted. It does not wait for the visualizer to start.
 func (i *Instance) Start() {
-	i.Stop()
-	ctx, cancel := context.WithCancel(i.parentCtx)
-	i.stop = cancel
+	i.mu.Lock()
+	defer i.mu.Unlock()
 
-	cfg := i.convertConfig(i.config)
-
-	i.wg.Add(1)
-	go func() {
-		defer i.wg.Done()
-		if err := catnip.Run(&cfg, ctx); err != nil {
-			i.error(err)
-		}
-	}()
+	i.want = true
+	i.restartLocked()
 }
 
 // Stop stops the catnip visualizer. It does not wait for it to finish.
 func (i *Instance) Stop() {
-	if i.stop != nil {
-		i.stop()
+	i.mu.Lock()
+	i.want = false
+	i.mu.Unlock()
+
+	i.wake()
+}
+
+// SetHidden sets whether the display is hidden, such as when its window is
+// minimized. A hidden display does not need any input, so catnip is stopped
+// until it is shown again if PauseWhenIdle is set.
+func (i *Instance) SetHidden(hidden bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.hidden != hidden {
+		i.hidden = hidden
+		i.wake()
+	}
+}
+
+// restartLocked asks loop to restart the run. i.mu must be held.
+func (i *Instance) restartLocked() {
+	i.restart = true
+	i.wake()
+}
+
+// wake wakes up loop to act on the changed fields.
+func (i *Instance) wake() {
+	select {
+	case i.kick <- struct{}{}:
+	default:
+		// Already woken up.
 	}
 }
@@ -0,0 +1,182 @@
+package catnipctl
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+func newTestInstance(t *testing.T) *Instance {
+	config := catnipgtk.DefaultConfig()
+	config.Backend = fakeBackendName
+	config.Device = ""
+	config.PauseWhenIdle = true
+
+	i := NewInstance(context.Background(), config, &fakeDisplay{})
+	i.SetSaveFunc(nil)
+	return i
+}
+
+// waitFor polls f until it returns true or a few seconds have passed.
+func waitFor(t *testing.T, what string, f func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !f() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestInstanceConcurrent(t *testing.T) {
+	testBackend.reset()
+	iterateMainContext(t)
+
+	i := newTestInstance(t)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		g := g
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for n := 0; n < 50; n++ {
+				switch (g + n) % 5 {
+				case 0:
+					i.Start()
+				case 1:
+					i.Stop()
+				case 2:
+					// Not a display-only change, so it restarts the run.
+					sampleSize := 512 + 512*(n%2)
+					i.Update(func(c *catnipgtk.Config) { c.SampleSize = sampleSize })
+				case 3:
+					i.SetHidden(n%2 == 0)
+				case 4:
+					i.State()
+				}
+				time.Sleep(time.Duration(g) * 100 * time.Microsecond)
+			}
+		}()
+	}
+	wg.Wait()
+
+	i.SetHidden(false)
+	i.Start()
+	waitFor(t, "the run to start", func() bool { return testBackend.live.Load() == 1 })
+
+	i.Finalize()
+
+	if live := testBackend.live.Load(); live != 0 {
+		t.Errorf("%d sessions still running after Finalize", live)
+	}
+	if inits, closes := testBackend.inits.Load(), testBackend.closes.Load(); inits != closes {
+		t.Errorf("backend initialized %d times but closed %d times", inits, closes)
+	}
+	if maxLive := testBackend.maxLive.Load(); maxLive > 1 {
+		t.Errorf("%d sessions were running at once", maxLive)
+	}
+	if started := testBackend.started.Load(); started == 0 {
+		t.Error("no session was ever started")
+	}
+}
+
+func TestInstanceFinalizeWaits(t *testing.T) {
+	testBackend.reset()
+	iterateMainContext(t)
+
+	i := newTestInstance(t)
+	i.Start()
+	waitFor(t, "the run to start", func() bool { return i.State() == StateRunning })
+
+	i.Finalize()
+
+	if live := testBackend.live.Load(); live != 0 {
+		t.Errorf("%d sessions still running after Finalize", live)
+	}
+}
+
+func TestInstanceFinalizeWhileStarting(t *testing.T) {
+	testBackend.reset()
+
+	// Nothing iterates the main context, so the run waits in SetupFunc for
+	// the display to be set up.
+	i := newTestInstance(t)
+	i.Start()
+	waitFor(t, "the run to start", func() bool { return i.State() == StateStarting })
+
+	done := make(chan struct{})
+	go func() {
+		i.Finalize()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Finalize did not return")
+	}
+
+	if live := testBackend.live.Load(); live != 0 {
+		t.Errorf("%d sessions still running after Finalize", live)
+	}
+}
+
+func TestInstanceHiddenStops(t *testing.T) {
+	testBackend.reset()
+	iterateMainContext(t)
+
+	i := newTestInstance(t)
+	t.Cleanup(i.Finalize)
+
+	i.Start()
+	waitFor(t, "the run to start", func() bool { return testBackend.live.Load() == 1 })
+
+	i.SetHidden(true)
+	waitFor(t, "the run to stop", func() bool { return i.State() == StateStopped })
+	if live := testBackend.live.Load(); live != 0 {
+		t.Errorf("%d sessions running while hidden", live)
+	}
+
+	i.SetHidden(false)
+	waitFor(t, "the run to start again", func() bool { return testBackend.live.Load() == 1 })
+}
+
+func TestListDevicesSharesBackend(t *testing.T) {
+	testBackend.reset()
+	iterateMainContext(t)
+
+	if _, err := listDevices(fakeBackendName); err != nil {
+		t.Fatal(err)
+	}
+	if inits, closes := testBackend.inits.Load(), testBackend.closes.Load(); inits != 1 || closes != 1 {
+		t.Errorf("listing devices initialized the backend %d times and closed it %d times, want 1 and 1", inits, closes)
+	}
+
+	testBackend.reset()
+
+	i := newTestInstance(t)
+	i.Start()
+	waitFor(t, "the run to start", func() bool { return testBackend.live.Load() == 1 })
+
+	for n := 0; n < 3; n++ {
+		if _, err := listDevices(fakeBackendName); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if inits, closes := testBackend.inits.Load(), testBackend.closes.Load(); inits != 1 || closes != 0 {
+		t.Errorf("listing devices during a run initialized the backend %d times and closed it %d times, want 1 and 0", inits, closes)
+	}
+
+	i.Finalize()
+
+	if closes := testBackend.closes.Load(); closes != 1 {
+		t.Errorf("backend closed %d times after the run, want 1", closes)
+	}
+}
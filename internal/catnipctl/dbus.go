@@ -0,0 +1,226 @@
+package catnipctl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/noriah/catnip/input"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// DBusInterface is the name of the D-Bus interface exported by ExportDBus.
+const DBusInterface = "so.libdb.catnip_gtk4.Visualizer"
+
+// Config values are passed around as JSON strings using the same names as the
+// config file, so that D-Bus does not need to know about every field.
+const dbusIntrospection = `
+<node>
+	<interface name="so.libdb.catnip_gtk4.Visualizer">
+		<method name="Start"/>
+		<method name="Stop"/>
+		<method name="GetConfig">
+			<arg name="config" type="s" direction="out"/>
+		</method>
+		<method name="SetConfig">
+			<arg name="config" type="s" direction="in"/>
+		</method>
+		<method name="Get">
+			<arg name="field" type="s" direction="in"/>
+			<arg name="value" type="s" direction="out"/>
+		</method>
+		<method name="Set">
+			<arg name="field" type="s" direction="in"/>
+			<arg name="value" type="s" direction="in"/>
+		</method>
+		<method name="ListBackends">
+			<arg name="backends" type="as" direction="out"/>
+		</method>
+		<method name="ListDevices">
+			<arg name="backend" type="s" direction="in"/>
+			<arg name="devices" type="as" direction="out"/>
+		</method>
+		<signal name="ConfigChanged">
+			<arg name="config" type="s"/>
+		</signal>
+	</interface>
+</node>`
+
+const (
+	dbusErrorInvalidArgs = "org.freedesktop.DBus.Error.InvalidArgs"
+	dbusErrorFailed      = "org.freedesktop.DBus.Error.Failed"
+)
+
+// ExportDBus exports the instance as a DBusInterface object at the given path
+// on the connection. Changes made over D-Bus go through Update and are saved
+// like changes made in the preferences.
+func ExportDBus(conn *gio.DBusConnection, path string, i *Instance) error {
+	node, err := gio.NewDBusNodeInfoForXML(dbusIntrospection)
+	if err != nil {
+		return fmt.Errorf("catnipctl: failed to parse introspection data: %w", err)
+	}
+
+	d := &dbusObject{instance: i}
+
+	_, err = conn.RegisterObject(path, node.LookupInterface(DBusInterface), d.call, nil, nil)
+	if err != nil {
+		return fmt.Errorf("catnipctl: failed to export D-Bus object: %w", err)
+	}
+
+	i.ConnectConfigChanged(func(cfg catnipgtk.Config) {
+		b, err := json.Marshal(cfg)
+		if err != nil {
+			log.Println("failed to marshal config for D-Bus:", err)
+			return
+		}
+
+		if err := conn.EmitSignal("", path, DBusInterface, "ConfigChanged", stringTuple(string(b))); err != nil {
+			log.Println("failed to emit ConfigChanged:", err)
+		}
+	})
+
+	return nil
+}
+
+type dbusObject struct {
+	instance *Instance
+}
+
+func (d *dbusObject) call(
+	conn *gio.DBusConnection, sender, path, iface, method string,
+	params *glib.Variant, invocation *gio.DBusMethodInvocation) {
+
+	arg := func(i uint) string { return params.ChildValue(i).String() }
+
+	switch method {
+	case "Start":
+		d.instance.Start()
+		invocation.ReturnValue(nil)
+
+	case "Stop":
+		d.instance.Stop()
+		invocation.ReturnValue(nil)
+
+	case "GetConfig":
+		b, err := json.Marshal(d.instance.Config())
+		if err != nil {
+			invocation.ReturnDBusError(dbusErrorFailed, err.Error())
+			return
+		}
+		invocation.ReturnValue(stringTuple(string(b)))
+
+	case "SetConfig":
+		if err := d.update([]byte(arg(0))); err != nil {
+			invocation.ReturnDBusError(dbusErrorInvalidArgs, err.Error())
+			return
+		}
+		invocation.ReturnValue(nil)
+
+	case "Get":
+		value, err := configField(d.instance.Config(), arg(0))
+		if err != nil {
+			invocation.ReturnDBusError(dbusErrorInvalidArgs, err.Error())
+			return
+		}
+		invocation.ReturnValue(stringTuple(string(value)))
+
+	case "Set":
+		field := map[string]json.RawMessage{arg(0): json.RawMessage(arg(1))}
+		b, err := json.Marshal(field)
+		if err == nil {
+			err = d.update(b)
+		}
+		if err != nil {
+			invocation.ReturnDBusError(dbusErrorInvalidArgs, err.Error())
+			return
+		}
+		invocation.ReturnValue(nil)
+
+	case "ListBackends":
+		invocation.ReturnValue(glib.NewVariantTuple([]*glib.Variant{
+			glib.NewVariantStrv(input.GetAllBackendNames()),
+		}))
+
+	case "ListDevices":
+		if !input.HasBackend(arg(0)) {
+			invocation.ReturnDBusError(dbusErrorInvalidArgs, fmt.Sprintf("unknown backend %q", arg(0)))
+			return
+		}
+
+		names, err := listDevices(arg(0))
+		if err != nil {
+			invocation.ReturnDBusError(dbusErrorFailed, err.Error())
+			return
+		}
+
+		invocation.ReturnValue(glib.NewVariantTuple([]*glib.Variant{
+			glib.NewVariantStrv(names),
+		}))
+
+	default:
+		invocation.ReturnDBusError(
+			"org.freedesktop.DBus.Error.UnknownMethod",
+			fmt.Sprintf("unknown method %q", method))
+	}
+}
+
+// update merges the given JSON object into the config, then saves it. The
+// object is decoded while the instance is locked so that changes made in the
+// meantime are not lost.
+func (d *dbusObject) update(b []byte) error {
+	var err error
+	d.instance.Update(func(c *catnipgtk.Config) {
+		cfg := *c
+
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err = dec.Decode(&cfg); err != nil {
+			err = fmt.Errorf("invalid config: %w", err)
+			return
+		}
+
+		if err = cfg.Validate(); err != nil {
+			return
+		}
+
+		*c = cfg
+	})
+	if err != nil {
+		return err
+	}
+
+	d.instance.Save(func(err error) {
+		if err != nil {
+			log.Println("failed to save config:", err)
+		}
+	})
+
+	return nil
+}
+
+// configField returns the JSON value of the config field with the given JSON
+// name.
+func configField(cfg *catnipgtk.Config, name string) (json.RawMessage, error) {
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+
+	value, ok := fields[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown config field %q", name)
+	}
+	return value, nil
+}
+
+func stringTuple(s string) *glib.Variant {
+	return glib.NewVariantTuple([]*glib.Variant{glib.NewVariantString(s)})
+}
@@ -0,0 +1,188 @@
+package catnipctl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+const testObjectPath = "/so/libdb/catnip_gtk4/test"
+
+// startBus starts a private session bus and returns its address.
+func startBus(t *testing.T) string {
+	path, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+
+	cmd := exec.Command(path, "--session", "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal("cannot start dbus-daemon:", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatal("cannot read the bus address:", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+func connectBus(t *testing.T, address string) *gio.DBusConnection {
+	flags := gio.DBusConnectionFlagsAuthenticationClient | gio.DBusConnectionFlagsMessageBusConnection
+	conn, err := gio.NewDBusConnectionForAddressSync(context.Background(), address, flags, nil)
+	if err != nil {
+		t.Fatal("cannot connect to the bus:", err)
+	}
+	t.Cleanup(func() { conn.CloseSync(context.Background()) })
+	return conn
+}
+
+// dbusClient calls the methods of an object exported by ExportDBus.
+type dbusClient struct {
+	conn *gio.DBusConnection
+	dest string
+}
+
+func (c dbusClient) call(method string, args ...string) (*glib.Variant, error) {
+	var params *glib.Variant
+	if len(args) > 0 {
+		children := make([]*glib.Variant, len(args))
+		for i, arg := range args {
+			children[i] = glib.NewVariantString(arg)
+		}
+		params = glib.NewVariantTuple(children)
+	}
+
+	return c.conn.CallSync(
+		context.Background(), c.dest, testObjectPath, DBusInterface, method,
+		params, nil, gio.DBusCallFlagsNone, 5000)
+}
+
+func TestDBus(t *testing.T) {
+	address := startBus(t)
+	iterateMainContext(t)
+
+	server := connectBus(t, address)
+	client := dbusClient{conn: connectBus(t, address), dest: server.UniqueName()}
+
+	config := catnipgtk.DefaultConfig()
+	config.Backend = fakeBackendName
+
+	i := NewInstance(context.Background(), config, &fakeDisplay{})
+	i.SetSaveFunc(nil)
+	t.Cleanup(i.Finalize)
+
+	if err := ExportDBus(server, testObjectPath, i); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan catnipgtk.Config, 16)
+	client.conn.SignalSubscribe(
+		server.UniqueName(), DBusInterface, "ConfigChanged", testObjectPath, "", gio.DBusSignalFlagsNone,
+		func(_ *gio.DBusConnection, _, _, _, _ string, params *glib.Variant) {
+			var cfg catnipgtk.Config
+			if err := json.Unmarshal([]byte(params.ChildValue(0).String()), &cfg); err != nil {
+				t.Error("cannot decode ConfigChanged:", err)
+				return
+			}
+			changed <- cfg
+		})
+
+	t.Run("Get", func(t *testing.T) {
+		v, err := client.call("Get", "lineWidth")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var lineWidth float64
+		if err := json.Unmarshal([]byte(v.ChildValue(0).String()), &lineWidth); err != nil {
+			t.Fatal(err)
+		}
+		if lineWidth != config.LineWidth {
+			t.Errorf("lineWidth = %v, want %v", lineWidth, config.LineWidth)
+		}
+
+		if _, err := client.call("Get", "noSuchField"); err == nil {
+			t.Error("getting an unknown field succeeded")
+		}
+	})
+
+	t.Run("Set", func(t *testing.T) {
+		if _, err := client.call("Set", "drawStyle", "2"); err != nil {
+			t.Fatal(err)
+		}
+		if got := i.Config().DrawStyle; got != catnipgtk.DrawCircle {
+			t.Errorf("drawStyle = %v, want %v", got, catnipgtk.DrawCircle)
+		}
+
+		select {
+		case cfg := <-changed:
+			if cfg.DrawStyle != catnipgtk.DrawCircle {
+				t.Errorf("ConfigChanged has drawStyle %v, want %v", cfg.DrawStyle, catnipgtk.DrawCircle)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("ConfigChanged was not emitted")
+		}
+	})
+
+	t.Run("SetInvalid", func(t *testing.T) {
+		before := *i.Config()
+
+		for _, args := range [][2]string{
+			{"noSuchField", "1"},
+			{"lineWidth", `"wide"`},
+			{"lineWidth", "{"},
+		} {
+			if _, err := client.call("Set", args[0], args[1]); err == nil {
+				t.Errorf("Set(%q, %q) succeeded", args[0], args[1])
+			}
+		}
+
+		if after := *i.Config(); after != before {
+			t.Error("config changed after invalid Sets")
+		}
+	})
+
+	t.Run("SetKeepsConcurrentChanges", func(t *testing.T) {
+		i.Update(func(c *catnipgtk.Config) { c.GapWidth = 7 })
+		if _, err := client.call("Set", "lineWidth", "9"); err != nil {
+			t.Fatal(err)
+		}
+
+		cfg := i.Config()
+		if cfg.GapWidth != 7 || cfg.LineWidth != 9 {
+			t.Errorf("gapWidth, lineWidth = %v, %v, want 7, 9", cfg.GapWidth, cfg.LineWidth)
+		}
+	})
+
+	t.Run("ListDevices", func(t *testing.T) {
+		v, err := client.call("ListDevices", fakeBackendName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if devices := v.ChildValue(0).Strv(); !reflect.DeepEqual(devices, testBackend.devices) {
+			t.Errorf("devices = %q, want %q", devices, testBackend.devices)
+		}
+
+		if _, err := client.call("ListDevices", "noSuchBackend"); err == nil {
+			t.Error("listing the devices of an unknown backend succeeded")
+		}
+	})
+}
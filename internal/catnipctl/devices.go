@@ -0,0 +1,173 @@
+package catnipctl
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+// DeviceMonitorInterval is the default interval of a DeviceMonitor.
+const DeviceMonitorInterval = 2 * time.Second
+
+// DeviceMonitor keeps the list of devices of a backend current. None of the
+// catnip backends report changes, so the devices are listed every interval in
+// the background.
+//
+// Its methods may be called from any goroutine.
+type DeviceMonitor struct {
+	interval time.Duration
+
+	mu        sync.Mutex
+	backend   string
+	devices   []string
+	onChanged []func(devices []string)
+	cancel    context.CancelFunc
+}
+
+// NewDeviceMonitor creates a new DeviceMonitor for the named backend. It does
+// nothing until it is started.
+func NewDeviceMonitor(backend string, interval time.Duration) *DeviceMonitor {
+	return &DeviceMonitor{
+		backend:  backend,
+		interval: interval,
+	}
+}
+
+// Devices returns the names of the devices as of the last check.
+func (m *DeviceMonitor) Devices() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.devices
+}
+
+// ConnectChanged calls f on the main thread with the names of the devices
+// every time they change, including after the first check.
+func (m *DeviceMonitor) ConnectChanged(f func(devices []string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onChanged = append(m.onChanged, f)
+}
+
+// SetBackend changes the backend whose devices are monitored.
+func (m *DeviceMonitor) SetBackend(backend string) {
+	m.mu.Lock()
+	if m.backend == backend {
+		m.mu.Unlock()
+		return
+	}
+	m.backend = backend
+	m.devices = nil
+	running := m.cancel != nil
+	m.mu.Unlock()
+
+	if running {
+		// Check the new backend right away.
+		m.Stop()
+		m.Start()
+	}
+}
+
+// Start starts checking the devices. It does nothing if already started.
+func (m *DeviceMonitor) Start() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	go m.poll(ctx, m.backend)
+}
+
+// Stop stops checking the devices.
+func (m *DeviceMonitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.cancel != nil {
+		m.cancel()
+		m.cancel = nil
+	}
+}
+
+func (m *DeviceMonitor) poll(ctx context.Context, backendName string) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		devices, err := listDevices(backendName)
+		if err != nil {
+			log.Printf("cannot list devices of %s: %v", backendName, err)
+		} else {
+			m.update(ctx, devices)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *DeviceMonitor) update(ctx context.Context, devices []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Don't report the devices of a backend that is no longer monitored.
+	if ctx.Err() != nil || (m.devices != nil && equalStrings(m.devices, devices)) {
+		return
+	}
+	m.devices = devices
+
+	onChanged := m.onChanged
+	glib.IdleAdd(func() {
+		if ctx.Err() != nil {
+			return
+		}
+		for _, f := range onChanged {
+			f(devices)
+		}
+	})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// listDevices returns the names of the devices of the backend. The backend is
+// only initialized if no run is using it, and closed again afterwards.
+func listDevices(backendName string) ([]string, error) {
+	backend, release, err := acquireBackend(backendName)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	devices, err := backend.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, len(devices))
+	for i, device := range devices {
+		names[i] = device.String()
+	}
+	return names, nil
+}
@@ -0,0 +1,296 @@
+package catnipctl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+	"unsafe"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/noriah/catnip"
+	"github.com/noriah/catnip/fft"
+	"github.com/noriah/catnip/input"
+	"libdb.so/catnip-gtk4/internal/audiofile"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// VideoFormat is the format of an exported video.
+type VideoFormat string
+
+const (
+	// VideoY4M is uncompressed YUV4MPEG2 video with 4:4:4 chroma.
+	VideoY4M VideoFormat = "y4m"
+	// VideoRGBA is headerless RGBA frames, one byte per channel. It is meant
+	// to be piped into an encoder such as ffmpeg -f rawvideo -pix_fmt rgba.
+	VideoRGBA VideoFormat = "rgba"
+)
+
+// ExportOptions are options for ExportVideo.
+type ExportOptions struct {
+	// Format is the format that the frames are written in.
+	Format VideoFormat
+	// FrameRate is the number of frames per second of video.
+	FrameRate int
+	// Width and Height are the size of each frame in pixels.
+	Width, Height int
+	// Background and Foreground are the colors used in place of the window
+	// background and the theme's foreground color.
+	Background, Foreground catnipgtk.Color
+}
+
+// ExportVideo visualizes the audio decoded by wav and writes it as a video
+// into w. Unlike RenderFrames, it steps through the audio as fast as it can
+// instead of in real time, so the same input always gives the same video. The
+// sample rate of the audio overrides the one in the config.
+func ExportVideo(ctx context.Context, config catnipgtk.Config, wav *audiofile.WAV, w io.Writer, opts ExportOptions) error {
+	if opts.FrameRate <= 0 {
+		return fmt.Errorf("catnipctl: invalid frame rate %d", opts.FrameRate)
+	}
+
+	config.SampleRate = wav.SampleRate()
+
+	v := catnipgtk.NewImageVisualizer(config.SampleRate, config.SampleSize)
+	v.SetPalette(opts.Background, opts.Foreground)
+	applyDisplayConfig(v, config)
+
+	// The video's timestamps stand in for the wall clock.
+	var frame int
+	v.SetClock(func() time.Time {
+		return time.Unix(0, 0).Add(time.Duration(frame) * time.Second / time.Duration(opts.FrameRate))
+	})
+
+	cfg := newCatnipConfig(config, v)
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("catnip: %w", err)
+	}
+	defer cfg.CleanupFunc()
+
+	proc := newOfflineProcessor(&cfg)
+	video := newVideoWriter(w, opts)
+
+	// Every video frame shows the analysis of the SampleSize samples before
+	// it. The samples are shifted into the processor's buffers as we go.
+	chunk := input.MakeBuffers(config.ChannelCount, config.SampleSize)
+	var read int
+
+	for ; ; frame++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		want := int(math.Round(float64(frame) * config.SampleRate / float64(opts.FrameRate)))
+		for read < want {
+			n := want - read
+			if n > config.SampleSize {
+				n = config.SampleSize
+			}
+			for ch := range chunk {
+				chunk[ch] = chunk[ch][:n]
+			}
+
+			n, err := wav.Read(chunk)
+			if err != nil {
+				if errors.Is(err, io.EOF) {
+					return video.Flush()
+				}
+				return err
+			}
+
+			proc.push(chunk, n)
+			read += n
+
+			for ch := range chunk {
+				chunk[ch] = chunk[ch][:cap(chunk[ch])]
+			}
+		}
+
+		proc.process()
+
+		if err := video.WriteFrame(v.Render(opts.Width, opts.Height)); err != nil {
+			return fmt.Errorf("catnipctl: failed to write frame %d: %w", frame, err)
+		}
+	}
+}
+
+// offlineProcessor does what catnip's processor does for every frame, but is
+// driven by the caller instead of a ticker and the input backend.
+type offlineProcessor struct {
+	cfg     *catnip.Config
+	samples [][]input.Sample // latest SampleSize samples of each channel
+	window  [][]input.Sample // samples after windowing
+	fftBufs [][]complex128
+	barBufs [][]float64
+	plans   []*fft.Plan
+	bars    int
+}
+
+func newOfflineProcessor(cfg *catnip.Config) *offlineProcessor {
+	p := &offlineProcessor{
+		cfg:     cfg,
+		samples: input.MakeBuffers(cfg.ChannelCount, cfg.SampleSize),
+		window:  input.MakeBuffers(cfg.ChannelCount, cfg.SampleSize),
+		fftBufs: make([][]complex128, cfg.ChannelCount),
+		barBufs: input.MakeBuffers(cfg.ChannelCount, cfg.SampleSize),
+		plans:   make([]*fft.Plan, cfg.ChannelCount),
+	}
+
+	for ch := range p.fftBufs {
+		p.fftBufs[ch] = make([]complex128, cfg.SampleSize/2+1)
+		fft.InitPlan(&p.plans[ch], p.window[ch], p.fftBufs[ch])
+	}
+
+	return p
+}
+
+// push shifts the first n samples of each channel in buf into the window.
+func (p *offlineProcessor) push(buf [][]float64, n int) {
+	for ch, samples := range p.samples {
+		copy(samples, samples[n:])
+		copy(samples[len(samples)-n:], buf[ch][:n])
+	}
+}
+
+func (p *offlineProcessor) process() {
+	// The windower works in place, so keep the original samples around for
+	// the next frame.
+	input.CopyBuffers(p.window, p.samples)
+
+	for ch := range p.window {
+		if p.cfg.Windower != nil {
+			p.cfg.Windower(p.window[ch])
+		}
+		p.plans[ch].Execute()
+	}
+
+	if n := p.cfg.Output.Bins(p.cfg.ChannelCount); n != p.bars {
+		p.bars = p.cfg.Analyzer.Recalculate(n)
+	}
+
+	for ch, fftBuf := range p.fftBufs {
+		buf := p.barBufs[ch]
+		for i := range buf[:p.bars] {
+			buf[i] = p.cfg.Analyzer.ProcessBin(i, fftBuf)
+		}
+	}
+
+	if p.cfg.Smoother != nil {
+		p.cfg.Smoother.SmoothBuffers(p.barBufs)
+	}
+
+	p.cfg.Output.Write(p.barBufs, p.cfg.ChannelCount)
+}
+
+// videoWriter writes Cairo image surfaces as video frames.
+type videoWriter struct {
+	w      *bufio.Writer
+	opts   ExportOptions
+	planes []byte
+	header bool
+}
+
+func newVideoWriter(w io.Writer, opts ExportOptions) *videoWriter {
+	return &videoWriter{
+		w:    bufio.NewWriter(w),
+		opts: opts,
+	}
+}
+
+// WriteFrame writes the surface as the next frame.
+func (v *videoWriter) WriteFrame(surface *cairo.Surface) error {
+	surface.Flush()
+
+	switch v.opts.Format {
+	case VideoRGBA:
+		return v.writeRGBA(surface)
+	case VideoY4M:
+		return v.writeY4M(surface)
+	default:
+		return fmt.Errorf("unknown video format %q", v.opts.Format)
+	}
+}
+
+// Flush writes any buffered data.
+func (v *videoWriter) Flush() error {
+	return v.w.Flush()
+}
+
+func (v *videoWriter) writeRGBA(surface *cairo.Surface) error {
+	width, height := surface.Width(), surface.Height()
+	if len(v.planes) != width*height*4 {
+		v.planes = make([]byte, width*height*4)
+	}
+
+	forEachPixel(surface, func(i int, r, g, b, a byte) {
+		v.planes[i*4+0] = r
+		v.planes[i*4+1] = g
+		v.planes[i*4+2] = b
+		v.planes[i*4+3] = a
+	})
+
+	_, err := v.w.Write(v.planes)
+	return err
+}
+
+func (v *videoWriter) writeY4M(surface *cairo.Surface) error {
+	width, height := surface.Width(), surface.Height()
+
+	if !v.header {
+		fmt.Fprintf(v.w, "YUV4MPEG2 W%d H%d F%d:1 Ip A1:1 C444\n", width, height, v.opts.FrameRate)
+		v.header = true
+	}
+
+	npixels := width * height
+	if len(v.planes) != npixels*3 {
+		v.planes = make([]byte, npixels*3)
+	}
+
+	yPlane := v.planes[:npixels]
+	uPlane := v.planes[npixels : npixels*2]
+	vPlane := v.planes[npixels*2:]
+
+	forEachPixel(surface, func(i int, r, g, b, _ byte) {
+		// BT.601 limited range, which is what Y4M readers assume.
+		rf, gf, bf := float64(r), float64(g), float64(b)
+		yPlane[i] = byte(16 + (65.481*rf+128.553*gf+24.966*bf)/255)
+		uPlane[i] = byte(128 + (-37.797*rf-74.203*gf+112.0*bf)/255)
+		vPlane[i] = byte(128 + (112.0*rf-93.786*gf-18.214*bf)/255)
+	})
+
+	if _, err := io.WriteString(v.w, "FRAME\n"); err != nil {
+		return err
+	}
+	_, err := v.w.Write(v.planes)
+	return err
+}
+
+// forEachPixel calls f with every pixel of an ARGB32 image surface, from left
+// to right and top to bottom. The colors are unpremultiplied.
+func forEachPixel(surface *cairo.Surface, f func(i int, r, g, b, a byte)) {
+	data := surface.Data()
+	stride := surface.Stride()
+	width, height := surface.Width(), surface.Height()
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			// Cairo stores each pixel as a native-endian uint32.
+			pixel := *(*uint32)(unsafe.Pointer(&data[y*stride+x*4]))
+
+			a := byte(pixel >> 24)
+			r := byte(pixel >> 16)
+			g := byte(pixel >> 8)
+			b := byte(pixel)
+
+			if a != 0 && a != 0xFF {
+				r = byte(uint32(r) * 0xFF / uint32(a))
+				g = byte(uint32(g) * 0xFF / uint32(a))
+				b = byte(uint32(b) * 0xFF / uint32(a))
+			}
+
+			f(y*width+x, r, g, b, a)
+		}
+	}
+}
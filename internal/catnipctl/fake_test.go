@@ -0,0 +1,188 @@
+package catnipctl
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/noriah/catnip/input"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// fakeBackendName is the name that testBackend is registered as.
+const fakeBackendName = "catnipctl-test"
+
+var testBackend = &fakeBackend{
+	devices: []string{"Fake Microphone", "Fake Monitor"},
+}
+
+func init() {
+	input.RegisterBackend(fakeBackendName, testBackend)
+}
+
+// fakeBackend is an input backend whose sessions write silence until they are
+// stopped. It counts how many of its sessions are running at once.
+type fakeBackend struct {
+	devices []string
+
+	inits  atomic.Int32
+	closes atomic.Int32
+
+	live    atomic.Int32 // sessions running now
+	maxLive atomic.Int32 // most sessions running at once
+	started atomic.Int32 // sessions started in total
+}
+
+func (b *fakeBackend) Init() error {
+	b.inits.Add(1)
+	return nil
+}
+
+func (b *fakeBackend) Close() error {
+	b.closes.Add(1)
+	return nil
+}
+
+func (b *fakeBackend) Devices() ([]input.Device, error) {
+	devices := make([]input.Device, len(b.devices))
+	for i, name := range b.devices {
+		devices[i] = fakeDevice(name)
+	}
+	return devices, nil
+}
+
+func (b *fakeBackend) DefaultDevice() (input.Device, error) {
+	if len(b.devices) == 0 {
+		return nil, errors.New("no devices")
+	}
+	return fakeDevice(b.devices[0]), nil
+}
+
+func (b *fakeBackend) Start(cfg input.SessionConfig) (input.Session, error) {
+	return &fakeSession{backend: b}, nil
+}
+
+// reset zeroes the counters between tests.
+func (b *fakeBackend) reset() {
+	b.inits.Store(0)
+	b.closes.Store(0)
+	b.live.Store(0)
+	b.maxLive.Store(0)
+	b.started.Store(0)
+}
+
+type fakeDevice string
+
+func (d fakeDevice) String() string { return string(d) }
+
+type fakeSession struct {
+	backend *fakeBackend
+}
+
+func (s *fakeSession) Start(ctx context.Context, buf [][]input.Sample, kick chan bool, mu *sync.Mutex) error {
+	b := s.backend
+	b.started.Add(1)
+
+	live := b.live.Add(1)
+	defer b.live.Add(-1)
+
+	for {
+		most := b.maxLive.Load()
+		if live <= most || b.maxLive.CompareAndSwap(most, live) {
+			break
+		}
+	}
+
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		mu.Lock()
+		for _, ch := range buf {
+			for i := range ch {
+				ch[i] = 0
+			}
+		}
+		mu.Unlock()
+
+		select {
+		case kick <- true:
+		default:
+		}
+	}
+}
+
+// fakeDisplay is a display that draws nothing. It is never used as a widget.
+type fakeDisplay struct {
+	gtk.Widgetter
+	writes atomic.Int32
+}
+
+var _ catnipgtk.Display = (*fakeDisplay)(nil)
+
+func (d *fakeDisplay) AsOutput() catnipgtk.DiscardableOutput {
+	return catnipgtk.WrapDiscardableOutput((*fakeOutput)(d))
+}
+
+func (d *fakeDisplay) SetSizes(bar, space float64)                 {}
+func (d *fakeDisplay) SetDrawStyle(style catnipgtk.DrawStyle)      {}
+func (d *fakeDisplay) SetLineCap(lineCap cairo.LineCap)            {}
+func (d *fakeDisplay) SetSamplingParams(rate float64, size int)    {}
+func (d *fakeDisplay) SetPeakCaps(time.Duration, float64, float64) {}
+func (d *fakeDisplay) SetScaling(scaling catnipgtk.Scaling)        {}
+func (d *fakeDisplay) SetAxes(show bool)                           {}
+func (d *fakeDisplay) SetPauseWhenIdle(pause bool)                 {}
+func (d *fakeDisplay) SetFrameRate(fps int)                        {}
+func (d *fakeDisplay) SetInterpolation(interpolate bool)           {}
+
+func (d *fakeDisplay) SetWaterfallParams(int, catnipgtk.ScrollDirection, catnipgtk.ColorMap) {}
+func (d *fakeDisplay) SetColors(catnipgtk.ColorMode, catnipgtk.Gradient, catnipgtk.Gradient) {}
+func (d *fakeDisplay) SetFrequencyMapping(mapping catnipgtk.FrequencyMapping)                {}
+
+type fakeOutput fakeDisplay
+
+func (o *fakeOutput) Bins(nchannels int) int { return 64 }
+
+func (o *fakeOutput) Write(bins [][]float64, nchannels int) error {
+	o.writes.Add(1)
+	return nil
+}
+
+// iterateMainContext iterates the default main context on its own thread until
+// the test finishes, so that glib.IdleAdd callbacks and D-Bus calls are
+// dispatched while the test blocks.
+func iterateMainContext(t *testing.T) {
+	var stop atomic.Bool
+	done := make(chan struct{})
+
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(done)
+
+		ctx := glib.MainContextDefault()
+		for !stop.Load() {
+			ctx.Iteration(true)
+		}
+	}()
+
+	t.Cleanup(func() {
+		stop.Store(true)
+		// Wake up the blocked iteration.
+		glib.IdleAdd(func() {})
+		<-done
+	})
+}
@@ -0,0 +1,75 @@
+package catnipctl
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// Profile returns the name of the active profile, or an empty string if the
+// default config is used.
+func (i *Instance) Profile() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.profile
+}
+
+// SetProfile sets the name of the active profile without loading its config,
+// such as when the profile is renamed.
+func (i *Instance) SetProfile(name string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.profile = name
+}
+
+// ConnectProfileChanged calls f after SwitchProfile switches to another
+// profile.
+func (i *Instance) ConnectProfileChanged(f func(name string)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.onProfileChanged = append(i.onProfileChanged, f)
+}
+
+// SwitchProfile restores the config of the named profile and applies it using
+// Update, so only the parts that changed are restarted. An empty name switches
+// back to the default config. Unlike most methods, it must be called from the
+// main thread, since it flushes the DefaultSaver.
+func (i *Instance) SwitchProfile(name string) error {
+	// Changes to the profile being switched to may not be saved yet.
+	if err := catnipgtk.DefaultSaver.Flush(); err != nil {
+		log.Println("failed to save config before switching profiles:", err)
+	}
+
+	var cfg catnipgtk.Config
+	var err error
+
+	if name == "" {
+		cfg, err = catnipgtk.RestoreConfig()
+		if errors.Is(err, fs.ErrNotExist) {
+			cfg, err = catnipgtk.DefaultConfig(), nil
+		}
+	} else {
+		cfg, err = catnipgtk.RestoreProfile(name)
+	}
+	if err != nil {
+		return err
+	}
+
+	i.SetProfile(name)
+	i.Update(func(c *catnipgtk.Config) { *c = cfg })
+
+	i.mu.Lock()
+	onProfileChanged := i.onProfileChanged
+	i.mu.Unlock()
+
+	for _, f := range onProfileChanged {
+		f(name)
+	}
+
+	return nil
+}
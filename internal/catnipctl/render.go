@@ -0,0 +1,85 @@
+package catnipctl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// RenderOptions are options for RenderFrames.
+type RenderOptions struct {
+	// Dir is the directory that the frames are written to. It is created if
+	// it does not exist.
+	Dir string
+	// FrameRate is the number of frames written per second.
+	FrameRate float64
+	// Width and Height are the size of each frame in pixels.
+	Width, Height int
+	// Duration is how long to render for. If it is 0, frames are rendered
+	// until the context is canceled.
+	Duration time.Duration
+	// Background and Foreground are the colors used in place of the window
+	// background and the theme's foreground color.
+	Background, Foreground catnipgtk.Color
+}
+
+// RenderFrames runs the catnip visualizer without a window and writes the
+// frames into opts.Dir as numbered PNG files. It does not need GTK to be
+// initialized.
+func RenderFrames(ctx context.Context, config catnipgtk.Config, opts RenderOptions) error {
+	if opts.FrameRate <= 0 {
+		return fmt.Errorf("catnipctl: invalid frame rate %v", opts.FrameRate)
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return fmt.Errorf("catnipctl: failed to create frames directory: %w", err)
+	}
+
+	v := catnipgtk.NewImageVisualizer(config.SampleRate, config.SampleSize)
+	v.SetPalette(opts.Background, opts.Foreground)
+	applyDisplayConfig(v, config)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if opts.Duration > 0 {
+		// Don't use context.WithTimeout, since catnip only treats
+		// context.Canceled as a clean stop.
+		timer := time.AfterFunc(opts.Duration, cancel)
+		defer timer.Stop()
+	}
+
+	cfg := newCatnipConfig(config, v)
+	runErr := make(chan error, 1)
+	go func() {
+		if err := runCatnip(ctx, &cfg, nil); err != nil {
+			runErr <- fmt.Errorf("catnip: %w", err)
+			return
+		}
+		runErr <- nil
+	}()
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.FrameRate))
+	defer ticker.Stop()
+
+	for frame := 0; ; frame++ {
+		select {
+		case <-ctx.Done():
+			return <-runErr
+		case err := <-runErr:
+			return err
+		case <-ticker.C:
+		}
+
+		name := filepath.Join(opts.Dir, fmt.Sprintf("frame-%06d.png", frame))
+		if err := v.Render(opts.Width, opts.Height).WriteToPNG(name); err != nil {
+			cancel()
+			<-runErr
+			return fmt.Errorf("catnipctl: failed to write frame %d: %w", frame, err)
+		}
+	}
+}
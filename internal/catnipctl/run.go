@@ -0,0 +1,153 @@
+package catnipctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/noriah/catnip"
+	"github.com/noriah/catnip/input"
+	"github.com/noriah/catnip/processor"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// runCatnip does what catnip.Run does, but also writes the samples of every
+// buffer to samples if it is not nil. The samples are taken from the input
+// session before catnip's processor sees them, since the processor windows
+// its buffers in place.
+func runCatnip(ctx context.Context, cfg *catnip.Config, samples catnipgtk.SampleOutput) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	inputBuffers := input.MakeBuffers(cfg.ChannelCount, cfg.SampleSize)
+
+	procConfig := processor.Config{
+		SampleRate:   cfg.SampleRate,
+		SampleSize:   cfg.SampleSize,
+		ChannelCount: cfg.ChannelCount,
+		ProcessRate:  cfg.ProcessRate,
+		Buffers:      inputBuffers,
+		Analyzer:     cfg.Analyzer,
+		Output:       cfg.Output,
+		Smoother:     cfg.Smoother,
+		Windower:     cfg.Windower,
+	}
+
+	var vis processor.Processor
+	if cfg.UseThreaded {
+		vis = processor.NewThreaded(procConfig)
+	} else {
+		vis = processor.New(procConfig)
+	}
+
+	backend, release, err := acquireBackend(cfg.Backend)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	sessConfig := input.SessionConfig{
+		FrameSize:  cfg.ChannelCount,
+		SampleSize: cfg.SampleSize,
+		SampleRate: cfg.SampleRate,
+	}
+
+	if sessConfig.Device, err = input.GetDevice(backend, cfg.Device); err != nil {
+		return err
+	}
+
+	audio, err := backend.Start(sessConfig)
+	if err != nil {
+		return fmt.Errorf("failed to start the input backend: %w", err)
+	}
+
+	if samples != nil {
+		audio = &sampleSession{
+			Session: audio,
+			config:  sessConfig,
+			output:  samples,
+		}
+	}
+
+	if cfg.SetupFunc != nil {
+		if err := cfg.SetupFunc(); err != nil {
+			return err
+		}
+	}
+
+	if cfg.CleanupFunc != nil {
+		defer cfg.CleanupFunc()
+	}
+
+	if cfg.StartFunc != nil {
+		if ctx, err = cfg.StartFunc(ctx); err != nil {
+			return err
+		}
+	}
+
+	kickChan := make(chan bool, 1)
+	mu := &sync.Mutex{}
+
+	ctx = vis.Start(ctx, kickChan, mu)
+	defer vis.Stop()
+
+	if err := audio.Start(ctx, inputBuffers, kickChan, mu); err != nil {
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			return fmt.Errorf("failed to start input session: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// sampleSession wraps an input session to write the samples of every buffer to
+// an output. The session writes into buffers of its own, which are copied into
+// the processor's buffers before it is kicked.
+type sampleSession struct {
+	input.Session
+	config input.SessionConfig
+	output catnipgtk.SampleOutput
+}
+
+func (s *sampleSession) Start(ctx context.Context, dst [][]input.Sample, kickChan chan bool, mu *sync.Mutex) error {
+	raw := input.MakeBuffers(s.config.FrameSize, s.config.SampleSize)
+	var rawMu sync.Mutex
+	rawKick := make(chan bool, 1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-rawKick:
+			}
+
+			rawMu.Lock()
+			mu.Lock()
+			input.CopyBuffers(dst, raw)
+			mu.Unlock()
+			s.output.WriteSamples(raw)
+			rawMu.Unlock()
+
+			select {
+			case kickChan <- true:
+			default:
+				// The processor has yet to see the last buffer.
+			}
+		}
+	}()
+
+	err := s.Session.Start(ctx, raw, rawKick, &rawMu)
+	cancel()
+	<-done
+
+	return err
+}
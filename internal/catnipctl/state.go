@@ -0,0 +1,285 @@
+package catnipctl
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// State is the state of the catnip run of an Instance.
+type State int
+
+const (
+	// StateStopped means that nothing is running.
+	StateStopped State = iota
+	// StateStarting means that the input is being set up.
+	StateStarting
+	// StateRunning means that the visualizer is running.
+	StateRunning
+	// StateStopping means that the run was stopped and is cleaning up. A new
+	// run is only started once it is done.
+	StateStopping
+	// StateRetrying means that the last run failed, such as because the input
+	// was lost, and that it is started again after a delay. See LastError.
+	StateRetrying
+)
+
+// minRetryDelay and maxRetryDelay are the bounds of the delay before a failed
+// run is retried. The delay doubles after every failure.
+const (
+	minRetryDelay = 1 * time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+// String returns the name of the state.
+func (s State) String() string {
+	switch s {
+	case StateStopped:
+		return "stopped"
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateStopping:
+		return "stopping"
+	case StateRetrying:
+		return "retrying"
+	default:
+		return "unknown"
+	}
+}
+
+// State returns the current state of the visualizer.
+func (i *Instance) State() State {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.state
+}
+
+// LastError returns the error that the last failed run failed with.
+func (i *Instance) LastError() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.lastErr
+}
+
+// ConnectStateChanged calls f on the main thread every time the state
+// changes.
+func (i *Instance) ConnectStateChanged(f func(State)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.onStateChanged = append(i.onStateChanged, f)
+}
+
+func (i *Instance) setState(state State) {
+	i.setStateIf(-1, state)
+}
+
+// setStateIf changes the state to the given one if the current state is from,
+// or in any case if from is -1.
+func (i *Instance) setStateIf(from, to State) {
+	i.mu.Lock()
+	if (from != -1 && i.state != from) || i.state == to {
+		i.mu.Unlock()
+		return
+	}
+	i.state = to
+	onStateChanged := i.onStateChanged
+	i.mu.Unlock()
+
+	glib.IdleAdd(func() {
+		for _, f := range onStateChanged {
+			f(to)
+		}
+	})
+}
+
+// run is a single catnip run.
+type run struct {
+	cancel    context.CancelFunc
+	cancelled bool
+	started   time.Time
+	done      chan error
+}
+
+// loop owns the catnip runs. It starts, stops and restarts them as the fields
+// guarded by i.mu ask it to, one at a time. Runs that fail are retried with
+// an exponential backoff.
+func (i *Instance) loop() {
+	defer i.wg.Done()
+
+	var current *run
+	// ended is true if the last run ended on its own without an error, such
+	// as a file that finished playing. It is not restarted until asked to.
+	var ended bool
+
+	var retry *time.Timer
+	retryDelay := minRetryDelay
+	stopRetrying := func() {
+		if retry != nil {
+			retry.Stop()
+			retry = nil
+		}
+	}
+
+	for {
+		var done chan error
+		if current != nil {
+			done = current.done
+		}
+
+		var retryC <-chan time.Time
+		if retry != nil {
+			retryC = retry.C
+		}
+
+		select {
+		case <-i.kick:
+		case <-retryC:
+			retry = nil
+		case err := <-done:
+			switch {
+			case current.cancelled:
+				// Stopped by us.
+			case err != nil:
+				// A run that lasted for a while had fixed whatever failed
+				// before, so start over with a short delay.
+				if time.Since(current.started) > maxRetryDelay {
+					retryDelay = minRetryDelay
+				}
+
+				log.Printf("catnip: %v; retrying in %v", err, retryDelay)
+				i.mu.Lock()
+				i.lastErr = err
+				i.mu.Unlock()
+
+				retry = time.NewTimer(retryDelay)
+				if retryDelay *= 2; retryDelay > maxRetryDelay {
+					retryDelay = maxRetryDelay
+				}
+			default:
+				ended = true
+			}
+			current = nil
+		}
+
+		i.mu.Lock()
+		want, restart, quitting := i.want, i.restart, i.quitting
+		cfg := i.config
+		if i.hidden && cfg.PauseWhenIdle {
+			// Stop until the display is shown again.
+			want = false
+		}
+		i.restart = false
+		i.mu.Unlock()
+
+		if restart {
+			// The config may have changed, so try again now.
+			ended = false
+			stopRetrying()
+			retryDelay = minRetryDelay
+		}
+
+		if current != nil {
+			if (quitting || !want || restart) && !current.cancelled {
+				current.cancel()
+				current.cancelled = true
+				i.setState(StateStopping)
+			}
+			// Wait for it to finish before doing anything else.
+			continue
+		}
+
+		switch {
+		case quitting:
+			stopRetrying()
+			i.setState(StateStopped)
+			return
+		case !want:
+			stopRetrying()
+			i.setState(StateStopped)
+		case retry != nil:
+			i.setState(StateRetrying)
+		case !ended:
+			i.setState(StateStarting)
+			current = i.start(cfg)
+		default:
+			i.setState(StateStopped)
+		}
+	}
+}
+
+// start starts a catnip run with the given config. Everything that may block,
+// such as checking the device, is done on the run's goroutine, so that the
+// loop stays responsive.
+func (i *Instance) start(c catnipgtk.Config) *run {
+	ctx, cancel := context.WithCancel(i.parentCtx)
+	r := &run{
+		cancel:  cancel,
+		started: time.Now(),
+		done:    make(chan error, 1),
+	}
+
+	cfg := i.convertConfig(ctx, c)
+
+	go func() {
+		var devices *DeviceMonitor
+		if !deviceAvailable(cfg.Backend, cfg.Device) {
+			log.Printf("device %q is gone, using the default device", cfg.Device)
+			cfg.Device = ""
+			devices = i.watchDevice(cfg.Backend, c.Device)
+		}
+
+		err := runCatnip(ctx, &cfg, sampleOutput(i.display))
+		if devices != nil {
+			devices.Stop()
+		}
+		r.done <- err
+	}()
+
+	return r
+}
+
+// watchDevice restarts the run once the device is plugged in again.
+func (i *Instance) watchDevice(backend, device string) *DeviceMonitor {
+	devices := NewDeviceMonitor(backend, DeviceMonitorInterval)
+	devices.ConnectChanged(func(names []string) {
+		for _, name := range names {
+			if name == device {
+				log.Printf("device %q is back, switching to it", name)
+				i.mu.Lock()
+				i.restartLocked()
+				i.mu.Unlock()
+				return
+			}
+		}
+	})
+	devices.Start()
+	return devices
+}
+
+// deviceAvailable returns whether the backend has the device, or true if it
+// cannot tell, so that catnip reports the error.
+func deviceAvailable(backend, device string) bool {
+	if device == "" {
+		return true
+	}
+
+	devices, err := listDevices(backend)
+	if err != nil {
+		return true
+	}
+
+	for _, d := range devices {
+		if d == device {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package catnipgtk
+
+import (
+	"math"
+
+	"github.com/noriah/catnip/dsp"
+)
+
+// squashLowFrequency is the frequency below which the analyzer squashes the
+// magnitudes, like catnip's own analyzer does.
+const squashLowFrequency = 600
+
+// Analyzer is a dsp.Analyzer that spreads the bins using a FrequencyMapping.
+// Each bin takes the largest magnitude of the FFT bins that it covers.
+type Analyzer struct {
+	sampleRate float64
+	sampleSize int
+	fftSize    int
+	mapping    FrequencyMapping
+	decibels   *DecibelScale
+
+	bins     []analyzerBin
+	binCount int
+}
+
+type analyzerBin struct {
+	floorFFT int
+	ceilFFT  int     // exclusive
+	weight   float64 // dB
+}
+
+var _ dsp.Analyzer = (*Analyzer)(nil)
+
+// NewAnalyzer creates a new analyzer. The mapping's range is clamped to what
+// can be sampled at the sample rate.
+func NewAnalyzer(sampleRate float64, sampleSize int, mapping FrequencyMapping) *Analyzer {
+	return &Analyzer{
+		sampleRate: sampleRate,
+		sampleSize: sampleSize,
+		fftSize:    sampleSize/2 + 1,
+		mapping:    mapping.Clamp(sampleRate),
+		bins:       make([]analyzerBin, sampleSize),
+	}
+}
+
+// SetDecibelScale makes the analyzer output levels in dBFS mapped to [0, 1]
+// using the given scale, instead of the natural log of the magnitudes.
+func (a *Analyzer) SetDecibelScale(scale DecibelScale) {
+	a.decibels = &scale
+	a.binCount = 0 // recalculate the weights
+}
+
+// Mapping returns the frequency mapping used by the analyzer.
+func (a *Analyzer) Mapping() FrequencyMapping {
+	return a.mapping
+}
+
+// BinCount implements dsp.Analyzer.
+func (a *Analyzer) BinCount() int {
+	return a.binCount
+}
+
+// Recalculate implements dsp.Analyzer.
+func (a *Analyzer) Recalculate(binCount int) int {
+	binCount = min(binCount, len(a.bins))
+	if binCount == a.binCount {
+		return binCount
+	}
+	a.binCount = binCount
+
+	for i := range a.bins[:binCount] {
+		lo, hi := a.mapping.Range(i, binCount)
+
+		floor := a.freqToIdx(lo, math.Floor)
+		ceil := a.freqToIdx(hi, math.Ceil)
+		// Always cover at least one FFT bin, even if the bin is narrower than
+		// the FFT's resolution.
+		if ceil <= floor {
+			ceil = floor + 1
+		}
+
+		a.bins[i] = analyzerBin{
+			floorFFT: min(floor, a.fftSize-1),
+			ceilFFT:  min(ceil, a.fftSize),
+		}
+
+		if a.decibels != nil {
+			a.bins[i].weight = a.decibels.Weighting.At(a.mapping.Center(i, binCount))
+		}
+	}
+
+	return binCount
+}
+
+// ProcessBin implements dsp.Analyzer.
+func (a *Analyzer) ProcessBin(idx int, src []complex128) float64 {
+	bin := a.bins[idx]
+
+	var mag float64
+	for _, c := range src[bin.floorFFT:bin.ceilFFT] {
+		mag = math.Max(mag, math.Hypot(real(c), imag(c)))
+	}
+
+	if a.decibels != nil {
+		// A full-scale sine wave has a magnitude of half the sample size.
+		db := 20*math.Log10(mag/(float64(a.sampleSize)/2)) + bin.weight
+		return a.decibels.normalize(db)
+	}
+
+	// Squash the low end a bit, since it tends to overpower everything else.
+	if f := a.freqToIdx(squashLowFrequency, math.Floor); bin.floorFFT < f {
+		mag *= 0.55 * math.Min(1, float64(bin.floorFFT+1)/float64(f))
+	}
+
+	if mag <= 0 {
+		return 0
+	}
+
+	return math.Max(0, math.Log(mag))
+}
+
+// logMagnitudeToDecibels converts a value returned by ProcessBin when not in dB
+// mode back to a level in dBFS. It ignores the squashing of the low end.
+func logMagnitudeToDecibels(v float64, sampleSize int) float64 {
+	return 20*v/math.Ln10 - 20*math.Log10(float64(sampleSize)/2)
+}
+
+func (a *Analyzer) freqToIdx(freq float64, round func(float64) float64) int {
+	idx := int(round(freq / (a.sampleRate / float64(a.sampleSize))))
+	return max(0, min(idx, a.fftSize-1))
+}
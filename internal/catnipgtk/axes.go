@@ -0,0 +1,261 @@
+package catnipgtk
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotkit/gtkutil/cssutil"
+)
+
+var _ = cssutil.WriteCSS(`
+	.catnip-axis-grid {
+		color: alpha(@theme_fg_color, 0.15);
+	}
+	.catnip-axis-label {
+		color: alpha(@theme_fg_color, 0.6);
+	}
+`)
+
+// axisFrequencies are the frequencies that may get a tick. Ticks that would
+// overlap the previous one are skipped.
+var axisFrequencies = []float64{
+	20, 30, 40, 50, 60, 80,
+	100, 200, 300, 400, 500, 600, 800,
+	1000, 2000, 3000, 4000, 5000, 6000, 8000,
+	10000, 12000, 16000, 20000, 24000,
+}
+
+// axisDecibelStep is the distance between the dB gridlines.
+const axisDecibelStep = 10
+
+// axisPadding is the space around the labels in pixels.
+const axisPadding = 4
+
+// frequencySpan is a stretch of the display where the bins of a channel are
+// laid out in a line.
+type frequencySpan struct {
+	ch    int
+	nbins int
+	left  float64 // x of the left edge of the first bin
+	step  float64 // width of a bin, negative if the bins go right to left
+	x0    float64 // visible range
+	x1    float64
+}
+
+// x returns the x coordinate of the given position on the frequency scale, as
+// returned by FrequencyMapping.Position.
+func (s frequencySpan) x(position float64) float64 {
+	return s.left + position*float64(s.nbins)*s.step
+}
+
+// bin returns the bin under the given x coordinate. It returns false if x is
+// not within the span.
+func (s frequencySpan) bin(x float64) (int, bool) {
+	if x < s.x0 || x >= s.x1 {
+		return 0, false
+	}
+	bin := int(math.Floor((x - s.left) / s.step))
+	if bin < 0 || bin >= s.nbins {
+		return 0, false
+	}
+	return bin, true
+}
+
+// frequencySpans returns how the bins are laid out across the display for the
+// current draw style. The lock must be held.
+func (d *spectrum) frequencySpans(wf float64) []frequencySpan {
+	nbars := d.bins(d.nchannels)
+	if nbars <= 0 || d.binWidth <= 0 {
+		return nil
+	}
+
+	switch d.drawStyle {
+	case DrawBottomBars:
+		// See drawBottomBars. The first channel takes up all the columns that
+		// it can, so only its bins are laid out.
+		xColMax := math.Round(wf/d.binWidth) * d.binWidth
+		left := (wf - xColMax) / 2
+		return []frequencySpan{{
+			ch:    0,
+			nbins: nbars,
+			left:  left,
+			step:  d.binWidth,
+			x0:    left,
+			x1:    math.Min(left+float64(nbars)*d.binWidth, wf),
+		}}
+
+	case DrawLines:
+		// See drawLines. The first channel goes left to right and the second
+		// channel comes back from the right edge, meeting in the middle.
+		if nbars < 3 {
+			return nil
+		}
+		barCount := math.Min(math.Round(wf/d.binWidth), float64((nbars-2)*d.nchannels))
+		step := wf / barCount
+		middle := float64(nbars-1) * step
+
+		spans := []frequencySpan{{
+			ch:    0,
+			nbins: nbars,
+			left:  -step / 2,
+			step:  step,
+			x0:    0,
+			x1:    middle,
+		}}
+		if d.nchannels > 1 {
+			spans = append(spans, frequencySpan{
+				ch:    1,
+				nbins: nbars,
+				left:  float64(2*nbars-3)*step + step/2,
+				step:  -step,
+				x0:    middle,
+				x1:    wf,
+			})
+		}
+		return spans
+
+	case DrawWaterfall:
+		// See pushWaterfallFrame. Every channel gets an equal share of the
+		// width, and every other channel is mirrored.
+		width := wf / float64(max(d.nchannels, 1))
+		spans := make([]frequencySpan, d.nchannels)
+		for ch := range spans {
+			x0 := float64(ch) * width
+			spans[ch] = frequencySpan{
+				ch:    ch,
+				nbins: nbars,
+				left:  x0,
+				step:  width / float64(nbars),
+				x0:    x0,
+				x1:    x0 + width,
+			}
+			if ch%2 == 1 {
+				spans[ch].left = x0 + width
+				spans[ch].step = -spans[ch].step
+			}
+		}
+		return spans
+
+	default:
+		return nil
+	}
+}
+
+// SetAxes sets whether the frequency and dB axes are drawn over the spectrum.
+func (d *spectrum) SetAxes(show bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.axes = show
+	d.invalidate()
+}
+
+// SetFrequencyMapping sets the mapping that the analyzer uses, which is used to
+// label the bins.
+func (d *spectrum) SetFrequencyMapping(mapping FrequencyMapping) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.mapping = mapping
+	d.invalidate()
+}
+
+// drawAxes draws the frequency ticks along the bottom and, if the bins are in
+// dBFS, the dB gridlines. They are styled using the .catnip-axis-grid and
+// .catnip-axis-label CSS classes on the given widget.
+func (d *spectrum) drawAxes(widget *gtk.Widget, cr *cairo.Context, width, height int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if !d.axes {
+		return
+	}
+
+	wf := float64(width)
+	hf := float64(height)
+
+	styles := widget.StyleContext()
+
+	if !d.scaling.Auto {
+		d.drawDecibelAxis(widget, styles, cr, wf, hf)
+	}
+
+	for _, span := range d.frequencySpans(wf) {
+		d.drawFrequencyAxis(widget, styles, cr, span, hf)
+	}
+}
+
+func (d *spectrum) drawFrequencyAxis(widget *gtk.Widget, styles *gtk.StyleContext, cr *cairo.Context, span frequencySpan, hf float64) {
+	// Keep track of the last label so that they don't overlap.
+	lastEdge := math.Inf(-1)
+	if span.step < 0 {
+		lastEdge = math.Inf(1)
+	}
+
+	for _, freq := range axisFrequencies {
+		if freq < d.mapping.Min || freq > d.mapping.Max {
+			continue
+		}
+
+		x := span.x(d.mapping.Position(freq))
+		if x < span.x0 || x > span.x1 {
+			continue
+		}
+
+		layout := widget.CreatePangoLayout(formatFrequency(freq))
+		w, h := layout.PixelSize()
+		half := float64(w)/2 + axisPadding
+
+		if span.step > 0 && x-half < lastEdge || span.step < 0 && x+half > lastEdge {
+			continue
+		}
+		if span.step > 0 {
+			lastEdge = x + half
+		} else {
+			lastEdge = x - half
+		}
+
+		styles.Save()
+		styles.AddClass("catnip-axis-grid")
+		gtk.RenderLine(styles, cr, x, 0, x, hf)
+		styles.Restore()
+
+		styles.Save()
+		styles.AddClass("catnip-axis-label")
+		gtk.RenderLayout(styles, cr, x-float64(w)/2, hf-float64(h)-axisPadding, layout)
+		styles.Restore()
+	}
+}
+
+func (d *spectrum) drawDecibelAxis(widget *gtk.Widget, styles *gtk.StyleContext, cr *cairo.Context, wf, hf float64) {
+	scale := d.scaling.Decibels
+	if scale.Ceiling <= scale.Floor {
+		return
+	}
+
+	top := math.Floor(scale.Ceiling/axisDecibelStep) * axisDecibelStep
+	for db := top; db > scale.Floor; db -= axisDecibelStep {
+		y := hf * (1 - scale.normalize(db))
+
+		styles.Save()
+		styles.AddClass("catnip-axis-grid")
+		gtk.RenderLine(styles, cr, 0, y, wf, y)
+		styles.Restore()
+
+		layout := widget.CreatePangoLayout(fmt.Sprintf("%.0f dB", db))
+
+		styles.Save()
+		styles.AddClass("catnip-axis-label")
+		gtk.RenderLayout(styles, cr, axisPadding, y+axisPadding, layout)
+		styles.Restore()
+	}
+}
+
+func formatFrequency(freq float64) string {
+	if freq >= 1000 {
+		return fmt.Sprintf("%gk", freq/1000)
+	}
+	return fmt.Sprintf("%g", freq)
+}
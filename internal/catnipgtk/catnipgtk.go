@@ -1,21 +1,23 @@
 package catnipgtk
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/diamondburned/gotk4/pkg/cairo"
-	"github.com/diamondburned/gotk4/pkg/core/glib"
 	"github.com/noriah/catnip/dsp"
 	"github.com/noriah/catnip/dsp/window"
 )
 
 // ConfigDir is the directory where the configuration is saved.
 var ConfigDir, _ = getConfigDir()
-var configFile = filepath.Join(ConfigDir, "config.json")
+
+// ConfigFile is the default configuration file.
+var ConfigFile = filepath.Join(ConfigDir, "config.json")
 
 func getConfigDir() (string, error) {
 	cfgDir, err := os.UserConfigDir()
@@ -45,6 +47,8 @@ func EnsureConfigDir() error {
 
 // Config is the configuration for the catnip instance.
 type Config struct {
+	Version int `json:"version"` // see ConfigVersion
+
 	Backend         string              `json:"backend"`
 	Device          string              `json:"device"`
 	SampleRate      float64             `json:"sampleRate"`
@@ -59,6 +63,89 @@ type Config struct {
 	GapWidth        float64             `json:"gapWidth"`
 	LineCap         cairo.LineCap       `json:"lineCap"`
 	WindowControls  bool                `json:"windowControls"`
+	ShowAxes        bool                `json:"showAxes"`
+
+	FrequencyScale FrequencyScale `json:"frequencyScale"`
+	MinFrequency   float64        `json:"minFrequency"` // Hz
+	MaxFrequency   float64        `json:"maxFrequency"` // Hz
+
+	MagnitudeScale MagnitudeScale `json:"magnitudeScale"`
+	DecibelFloor   float64        `json:"decibelFloor"`   // dBFS
+	DecibelCeiling float64        `json:"decibelCeiling"` // dBFS
+	Weighting      Weighting      `json:"weighting"`
+	ScalingWindow  float64        `json:"scalingWindow"` // seconds
+	PeakThreshold  float64        `json:"peakThreshold"`
+	ZeroThreshold  int            `json:"zeroThreshold"`
+
+	WaterfallHistory   int             `json:"waterfallHistory"`
+	WaterfallDirection ScrollDirection `json:"waterfallDirection"`
+	ColorMap           ColorMap        `json:"colorMap"`
+
+	PeakHoldTime     float64 `json:"peakHoldTime"`     // seconds
+	PeakFallRate     float64 `json:"peakFallRate"`     // display heights per second squared
+	PeakCapThickness float64 `json:"peakCapThickness"` // 0 disables peak caps
+
+	ColorMode          ColorMode `json:"colorMode"`
+	Gradient           Gradient  `json:"gradient"`      // left or both channels
+	RightGradient      Gradient  `json:"rightGradient"` // if SplitChannelColors
+	SplitChannelColors bool      `json:"splitChannelColors"`
+
+	Renderer Renderer `json:"renderer"`
+	// PauseWhenIdle stops drawing while the input is silent and stops catnip
+	// while the window is hidden.
+	PauseWhenIdle bool `json:"pauseWhenIdle"`
+	// FrameRate is the most frames drawn per second, or 0 to draw on every
+	// frame of the display. Frames are only drawn if there is new data.
+	FrameRate int `json:"frameRate"`
+	// Interpolate blends the bins between the frames of the analyzer, so that
+	// a ProcessRate below the FrameRate still animates smoothly.
+	Interpolate bool `json:"interpolate"`
+
+	// FileFolder and FileLoop are used by the file backend.
+	FileFolder string `json:"fileFolder"` // empty for ~/Music
+	FileLoop   bool   `json:"fileLoop"`
+}
+
+// PeakHoldDuration returns PeakHoldTime as a time.Duration.
+func (c Config) PeakHoldDuration() time.Duration {
+	return time.Duration(c.PeakHoldTime * float64(time.Second))
+}
+
+// FrequencyMapping returns the frequency mapping of the analyzer.
+func (c Config) FrequencyMapping() FrequencyMapping {
+	return FrequencyMapping{
+		Scale: c.FrequencyScale,
+		Min:   c.MinFrequency,
+		Max:   c.MaxFrequency,
+	}
+}
+
+// DecibelScale returns the scale used if MagnitudeScale is MagnitudeDecibel.
+func (c Config) DecibelScale() DecibelScale {
+	return DecibelScale{
+		Floor:     c.DecibelFloor,
+		Ceiling:   c.DecibelCeiling,
+		Weighting: c.Weighting,
+	}
+}
+
+// Scaling returns how the display should scale the bins.
+func (c Config) Scaling() Scaling {
+	return Scaling{
+		Auto:          c.MagnitudeScale != MagnitudeDecibel,
+		Window:        c.ScalingWindow,
+		PeakThreshold: c.PeakThreshold,
+		ZeroThreshold: c.ZeroThreshold,
+		Decibels:      c.DecibelScale(),
+	}
+}
+
+// ChannelGradients returns the gradients of the left and right channels.
+func (c Config) ChannelGradients() (left, right Gradient) {
+	if c.SplitChannelColors {
+		return c.Gradient, c.RightGradient
+	}
+	return c.Gradient, c.Gradient
 }
 
 // WindowFunc is the window function to use for the FFT.
@@ -85,6 +172,7 @@ var WindowFuncs = map[WindowFunc]window.Function{
 // DefaultConfig returns the default configuration.
 func DefaultConfig() Config {
 	return Config{
+		Version:         ConfigVersion,
 		Backend:         "pipewire",
 		Device:          "",
 		SampleRate:      44100,
@@ -97,6 +185,42 @@ func DefaultConfig() Config {
 		LineWidth:       3,
 		GapWidth:        3,
 		LineCap:         cairo.LineCapRound,
+
+		// The same range as catnip's own analyzer.
+		FrequencyScale: FrequencyLog,
+		MinFrequency:   60,
+		MaxFrequency:   8000,
+
+		MagnitudeScale: MagnitudeAuto,
+		DecibelFloor:   -90,
+		DecibelCeiling: 0,
+		Weighting:      WeightingNone,
+		ScalingWindow:  ScalingWindow,
+		PeakThreshold:  PeakThreshold,
+		ZeroThreshold:  ZeroThreshold,
+
+		WaterfallHistory:   DefaultWaterfallHistory,
+		WaterfallDirection: ScrollDown,
+		ColorMap:           ColorMapInferno,
+
+		PeakHoldTime:     0.5,
+		PeakFallRate:     2,
+		PeakCapThickness: 0,
+
+		ColorMode: ColorTheme,
+		Gradient: NewGradient(
+			ColorStop{0, MustParseColor("#3584e4")},
+			ColorStop{1, MustParseColor("#c061cb")},
+		),
+		RightGradient: NewGradient(
+			ColorStop{0, MustParseColor("#e66100")},
+			ColorStop{1, MustParseColor("#f6d32d")},
+		),
+
+		Renderer:      RendererCairo,
+		PauseWhenIdle: true,
+
+		FileLoop: true,
 	}
 }
 
@@ -107,38 +231,63 @@ func RestoreConfig() (Config, error) {
 		return Config{}, err
 	}
 
-	f, err := os.Open(configFile)
+	return RestoreConfigFile(ConfigFile)
+}
+
+// RestoreConfigFile restores the configuration from the given file. Older
+// files are migrated, and fields that are missing are set to their defaults.
+// Invalid values are repaired and logged; see Config.Validate. It only returns
+// an error if the file cannot be read or decoded.
+func RestoreConfigFile(path string) (Config, error) {
+	config, invalid, err := ReadConfigFile(path)
 	if err != nil {
 		return Config{}, err
 	}
-	defer f.Close()
-
-	var config Config
-	if err := json.NewDecoder(f).Decode(&config); err != nil {
-		return Config{}, fmt.Errorf("catnipgtk: failed to decode config: %w", err)
+	if invalid != nil {
+		log.Printf("repaired config %s: %v", path, invalid)
 	}
+
 	return config, nil
 }
 
-// SaveAsync saves the configuration asynchronously.
-func (c Config) SaveAsync(done func(err error)) {
-	b, err := json.MarshalIndent(c, "", "  ")
+// ReadConfigFile is like RestoreConfigFile, but it returns the values that
+// were repaired as invalid instead of logging them. The config is usable
+// whenever err is nil.
+func ReadConfigFile(path string) (config Config, invalid, err error) {
+	b, err := os.ReadFile(path)
 	if err != nil {
-		done(err)
-		return
+		return Config{}, nil, err
+	}
+	return parseConfig(b)
+}
+
+// parseConfig decodes and validates a config file. invalid lists the values
+// that were repaired, and err is only returned if the file cannot be decoded.
+func parseConfig(b []byte) (config Config, invalid, err error) {
+	config, err = decodeConfig(b)
+	if err != nil {
+		return Config{}, nil, fmt.Errorf("catnipgtk: failed to decode config: %w", err)
 	}
 
-	go func() {
-		if err := EnsureConfigDir(); err != nil {
-			glib.IdleAdd(func() { done(err) })
-			return
-		}
+	return config, config.Validate(), nil
+}
 
-		if err := os.WriteFile(configFile, b, 0644); err != nil {
-			glib.IdleAdd(func() { done(err) })
-			return
-		}
-	}()
+// SaveAsync saves the configuration to the config file using the
+// DefaultSaver. It must be called on the main thread. done is called on the
+// main thread once the file is written, or once a newer config that replaced
+// this one is; see Saver.Save.
+func (c Config) SaveAsync(done func(err error)) {
+	if ConfigDir == "" {
+		done(errors.New("catnipgtk: ConfigDir is empty"))
+		return
+	}
+	c.SaveAsyncTo(ConfigFile, done)
+}
+
+// SaveAsyncTo saves the configuration to the given file using the
+// DefaultSaver.
+func (c Config) SaveAsyncTo(path string, done func(err error)) {
+	DefaultSaver.Save(path, c, done)
 }
 
 // ConfigOnlyChangedDisplay returns whether the only changed fields are
@@ -150,6 +299,24 @@ func ConfigOnlyChangedDisplay(old, new Config) bool {
 		cfg.LineWidth = 0
 		cfg.DrawStyle = 0
 		cfg.LineCap = 0
+		cfg.WaterfallHistory = 0
+		cfg.WaterfallDirection = ""
+		cfg.ColorMap = ""
+		cfg.PeakHoldTime = 0
+		cfg.PeakFallRate = 0
+		cfg.PeakCapThickness = 0
+		cfg.ColorMode = ""
+		cfg.Gradient = Gradient{}
+		cfg.RightGradient = Gradient{}
+		cfg.SplitChannelColors = false
+		cfg.Renderer = ""
+		cfg.ShowAxes = false
+		cfg.ScalingWindow = 0
+		cfg.PeakThreshold = 0
+		cfg.ZeroThreshold = 0
+		cfg.PauseWhenIdle = false
+		cfg.FrameRate = 0
+		cfg.Interpolate = false
 	}
 
 	zero(&old)
@@ -0,0 +1,72 @@
+package catnipgtk
+
+import "math"
+
+// ColorMap is a color map used to map magnitudes to colors.
+type ColorMap string
+
+const (
+	ColorMapGrayscale ColorMap = "Grayscale"
+	ColorMapInferno   ColorMap = "Inferno"
+	ColorMapViridis   ColorMap = "Viridis"
+	ColorMapMagma     ColorMap = "Magma"
+)
+
+// ColorMaps maps each ColorMap to its list of evenly spaced color stops.
+var ColorMaps = map[ColorMap][][3]float64{
+	ColorMapGrayscale: {
+		{0, 0, 0},
+		{1, 1, 1},
+	},
+	ColorMapInferno: {
+		{0.001, 0.000, 0.014},
+		{0.258, 0.039, 0.406},
+		{0.578, 0.148, 0.404},
+		{0.865, 0.317, 0.226},
+		{0.988, 0.645, 0.040},
+		{0.988, 0.998, 0.645},
+	},
+	ColorMapViridis: {
+		{0.267, 0.005, 0.329},
+		{0.231, 0.322, 0.545},
+		{0.129, 0.569, 0.549},
+		{0.369, 0.788, 0.384},
+		{0.993, 0.906, 0.144},
+	},
+	ColorMapMagma: {
+		{0.001, 0.000, 0.014},
+		{0.232, 0.060, 0.437},
+		{0.550, 0.161, 0.506},
+		{0.868, 0.288, 0.409},
+		{0.996, 0.624, 0.427},
+		{0.987, 0.991, 0.750},
+	},
+}
+
+// At returns the color at the given value, which is clamped to [0, 1]. Unknown
+// color maps are treated as ColorMapGrayscale.
+func (m ColorMap) At(v float64) (r, g, b float64) {
+	stops, ok := ColorMaps[m]
+	if !ok {
+		stops = ColorMaps[ColorMapGrayscale]
+	}
+
+	v = math.Max(0, math.Min(v, 1))
+
+	// Find the two stops around the value and linearly interpolate between
+	// them.
+	pos := v * float64(len(stops)-1)
+	i := int(pos)
+	if i >= len(stops)-1 {
+		last := stops[len(stops)-1]
+		return last[0], last[1], last[2]
+	}
+
+	t := pos - float64(i)
+	lo := stops[i]
+	hi := stops[i+1]
+
+	return lo[0] + (hi[0]-lo[0])*t,
+		lo[1] + (hi[1]-lo[1])*t,
+		lo[2] + (hi[2]-lo[2])*t
+}
@@ -0,0 +1,232 @@
+package catnipgtk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+)
+
+// ColorMode is how the bars and lines are colored.
+type ColorMode string
+
+const (
+	// ColorTheme colors everything using the .catnip-background CSS class,
+	// which defaults to the theme's foreground color.
+	ColorTheme ColorMode = "theme"
+	// ColorSolid colors everything using the first color stop.
+	ColorSolid ColorMode = "solid"
+	// ColorVertical colors using a gradient from the bottom to the top.
+	ColorVertical ColorMode = "vertical"
+	// ColorHorizontal colors using a gradient from the left to the right.
+	ColorHorizontal ColorMode = "horizontal"
+	// ColorMagnitude colors each bar by its magnitude. Lines are colored the
+	// same way as ColorVertical.
+	ColorMagnitude ColorMode = "magnitude"
+)
+
+// Color is an RGBA color. It is marshaled as a #rrggbbaa hex string.
+type Color struct {
+	R, G, B, A float64
+}
+
+// ParseColor parses a #rrggbb or #rrggbbaa hex string.
+func ParseColor(s string) (Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	var r, g, b, a uint8
+	if len(hex) != 8 {
+		return Color{}, fmt.Errorf("invalid color %q", s)
+	}
+	if _, err := fmt.Sscanf(hex, "%02x%02x%02x%02x", &r, &g, &b, &a); err != nil {
+		return Color{}, fmt.Errorf("invalid color %q: %w", s, err)
+	}
+
+	return Color{
+		R: float64(r) / 0xFF,
+		G: float64(g) / 0xFF,
+		B: float64(b) / 0xFF,
+		A: float64(a) / 0xFF,
+	}, nil
+}
+
+// MustParseColor is like ParseColor, but it panics on error.
+func MustParseColor(s string) Color {
+	c, err := ParseColor(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// String returns the color as a #rrggbbaa hex string.
+func (c Color) String() string {
+	return fmt.Sprintf("#%02x%02x%02x%02x",
+		uint8(c.R*0xFF+0.5), uint8(c.G*0xFF+0.5), uint8(c.B*0xFF+0.5), uint8(c.A*0xFF+0.5))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (c Color) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (c *Color) UnmarshalText(text []byte) error {
+	color, err := ParseColor(string(text))
+	if err != nil {
+		return err
+	}
+	*c = color
+	return nil
+}
+
+// ColorStop is a color at an offset within a Gradient.
+type ColorStop struct {
+	Offset float64 `json:"offset"`
+	Color  Color   `json:"color"`
+}
+
+// MaxColorStops is the maximum number of color stops in a Gradient. Gradients
+// are fixed-size so that Config stays comparable.
+const MaxColorStops = 8
+
+// Gradient is a list of up to MaxColorStops color stops. It is marshaled as a
+// JSON array.
+type Gradient struct {
+	stops [MaxColorStops]ColorStop
+	len   int
+}
+
+// NewGradient creates a new Gradient. Stops past MaxColorStops are ignored.
+func NewGradient(stops ...ColorStop) Gradient {
+	var g Gradient
+	g.len = copy(g.stops[:], stops)
+	return g
+}
+
+// Stops returns a copy of the color stops.
+func (g Gradient) Stops() []ColorStop {
+	return append([]ColorStop(nil), g.stops[:g.len]...)
+}
+
+// Len returns the number of color stops.
+func (g Gradient) Len() int {
+	return g.len
+}
+
+// At returns the color at the given offset. The stops are assumed to be
+// sorted by offset.
+func (g Gradient) At(offset float64) Color {
+	stops := g.stops[:g.len]
+	switch {
+	case len(stops) == 0:
+		return Color{1, 1, 1, 1}
+	case offset <= stops[0].Offset:
+		return stops[0].Color
+	}
+
+	for i := 1; i < len(stops); i++ {
+		lo := stops[i-1]
+		hi := stops[i]
+		if offset > hi.Offset {
+			continue
+		}
+
+		t := 0.0
+		if hi.Offset > lo.Offset {
+			t = (offset - lo.Offset) / (hi.Offset - lo.Offset)
+		}
+
+		return Color{
+			R: lo.Color.R + (hi.Color.R-lo.Color.R)*t,
+			G: lo.Color.G + (hi.Color.G-lo.Color.G)*t,
+			B: lo.Color.B + (hi.Color.B-lo.Color.B)*t,
+			A: lo.Color.A + (hi.Color.A-lo.Color.A)*t,
+		}
+	}
+
+	return stops[len(stops)-1].Color
+}
+
+// MarshalJSON implements json.Marshaler.
+func (g Gradient) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.Stops())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (g *Gradient) UnmarshalJSON(b []byte) error {
+	var stops []ColorStop
+	if err := json.Unmarshal(b, &stops); err != nil {
+		return err
+	}
+	*g = NewGradient(stops...)
+	return nil
+}
+
+// strokeColors sets the source of a Cairo context for drawing each channel.
+type strokeColors struct {
+	mode  ColorMode
+	left  Gradient
+	right Gradient
+}
+
+func (s *strokeColors) gradient(ch int) Gradient {
+	if ch%2 == 1 {
+		return s.right
+	}
+	return s.left
+}
+
+// setSource sets the source for drawing the given channel. The background
+// surface is used for ColorTheme and any unknown mode.
+func (s *strokeColors) setSource(cr *cairo.Context, ch int, background *cairo.Surface, wf, hf float64) {
+	gradient := s.gradient(ch)
+	if gradient.Len() == 0 {
+		cr.SetSourceSurface(background, 0, 0)
+		return
+	}
+
+	var pattern *cairo.Pattern
+	var err error
+
+	switch s.mode {
+	case ColorSolid:
+		c := gradient.stops[0].Color
+		cr.SetSourceRGBA(c.R, c.G, c.B, c.A)
+		return
+	case ColorVertical, ColorMagnitude:
+		pattern, err = cairo.NewPatternLinear(0, hf, 0, 0)
+	case ColorHorizontal:
+		pattern, err = cairo.NewPatternLinear(0, 0, wf, 0)
+	default:
+		cr.SetSourceSurface(background, 0, 0)
+		return
+	}
+
+	if err != nil {
+		cr.SetSourceSurface(background, 0, 0)
+		return
+	}
+
+	for _, stop := range gradient.stops[:gradient.len] {
+		pattern.AddColorStopRGBA(stop.Offset, stop.Color.R, stop.Color.G, stop.Color.B, stop.Color.A)
+	}
+	cr.SetSource(pattern)
+}
+
+// setMagnitude sets the source for drawing a bar of the given channel with the
+// given normalized magnitude. It does nothing unless the mode is
+// ColorMagnitude.
+func (s *strokeColors) setMagnitude(cr *cairo.Context, ch int, magnitude float64) {
+	gradient := s.gradient(ch)
+	if s.mode != ColorMagnitude || gradient.Len() == 0 {
+		return
+	}
+
+	c := gradient.At(magnitude)
+	cr.SetSourceRGBA(c.R, c.G, c.B, c.A)
+}
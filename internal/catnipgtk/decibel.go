@@ -0,0 +1,90 @@
+package catnipgtk
+
+import "math"
+
+// MagnitudeScale is how the magnitudes of the bins are scaled to the height of
+// the display.
+type MagnitudeScale string
+
+const (
+	// MagnitudeAuto scales the bins against their recent peaks, so that the
+	// display is always filled. See Scaling.
+	MagnitudeAuto MagnitudeScale = "auto"
+	// MagnitudeDecibel maps the level of each bin in dBFS from a fixed floor
+	// to a fixed ceiling. See DecibelScale.
+	MagnitudeDecibel MagnitudeScale = "dbfs"
+)
+
+// Weighting is a frequency weighting applied to the levels in dBFS.
+type Weighting string
+
+const (
+	// WeightingNone applies no weighting.
+	WeightingNone Weighting = ""
+	// WeightingA is A-weighting, which follows how loud quiet sounds are
+	// perceived.
+	WeightingA Weighting = "a"
+	// WeightingC is C-weighting, which follows how loud loud sounds are
+	// perceived.
+	WeightingC Weighting = "c"
+)
+
+// At returns the gain of the weighting at the given frequency in dB.
+func (w Weighting) At(freq float64) float64 {
+	const (
+		f1 = 20.598997
+		f2 = 107.65265
+		f3 = 737.86223
+		f4 = 12194.217
+	)
+
+	f := freq * freq
+
+	switch w {
+	case WeightingA:
+		r := f4 * f4 * f * f / ((f + f1*f1) * math.Sqrt((f+f2*f2)*(f+f3*f3)) * (f + f4*f4))
+		return 20*math.Log10(r) + 2.00
+	case WeightingC:
+		r := f4 * f4 * f / ((f + f1*f1) * (f + f4*f4))
+		return 20*math.Log10(r) + 0.06
+	default:
+		return 0
+	}
+}
+
+// DecibelScale maps levels in dBFS to the height of the display.
+type DecibelScale struct {
+	Floor     float64 // dBFS at the bottom of the display
+	Ceiling   float64 // dBFS at the top of the display
+	Weighting Weighting
+}
+
+// normalize maps the given level in dBFS to [0, 1].
+func (s DecibelScale) normalize(db float64) float64 {
+	if s.Ceiling <= s.Floor {
+		return 0
+	}
+	return math.Max(0, math.Min(1, (db-s.Floor)/(s.Ceiling-s.Floor)))
+}
+
+// denormalize maps the given value in [0, 1] back to a level in dBFS.
+func (s DecibelScale) denormalize(v float64) float64 {
+	return s.Floor + v*(s.Ceiling-s.Floor)
+}
+
+// Scaling is how a display scales the bins that it is given.
+type Scaling struct {
+	// Auto scales the bins against their recent peaks. If it is false, the
+	// bins are expected to be already normalized to [0, 1].
+	Auto bool
+	// Window is how far back in seconds the peaks are kept for.
+	Window float64
+	// PeakThreshold is the peak below which a frame is treated as silent and
+	// the scale is left alone.
+	PeakThreshold float64
+	// ZeroThreshold is the number of silent frames after which the display is
+	// considered idle.
+	ZeroThreshold int
+	// Decibels is the scale that the bins were mapped with if Auto is false.
+	Decibels DecibelScale
+}
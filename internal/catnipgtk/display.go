@@ -1,9 +1,13 @@
 package catnipgtk
 
 import (
+	"fmt"
 	"sync/atomic"
+	"time"
 
 	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 	"github.com/diamondburned/gotkit/gtkutil/cssutil"
 	"github.com/noriah/catnip/processor"
@@ -15,10 +19,16 @@ var _ = cssutil.WriteCSS(`
 	}
 `)
 
+// ScalingWindow, PeakThreshold and ZeroThreshold are the default values of
+// the fields in Scaling.
 const ScalingWindow = 1.5 // seconds
 const PeakThreshold = 0.01
 const ZeroThreshold = 5
 
+// CircleRadius is the radius of the ring drawn by DrawCircle as a fraction of
+// the smaller side of the display.
+const CircleRadius = 0.25
+
 // DrawStyle is the style of drawing.
 type DrawStyle int
 
@@ -27,11 +37,42 @@ const (
 	DrawBottomBars DrawStyle = iota
 	// DrawLines draws lines across the display.
 	DrawLines
+	// DrawCircle draws bars pointing outwards from a ring in the middle of
+	// the display.
+	DrawCircle
+	// DrawWaterfall draws a scrolling spectrogram of the past frames.
+	DrawWaterfall
+	// DrawOscilloscope draws the time-domain waveform of the input. It is
+	// drawn by OscilloscopeDisplay.
+	DrawOscilloscope
 )
 
+var drawStyleNames = map[string]DrawStyle{
+	"bars":         DrawBottomBars,
+	"lines":        DrawLines,
+	"circle":       DrawCircle,
+	"waterfall":    DrawWaterfall,
+	"oscilloscope": DrawOscilloscope,
+}
+
+// ParseDrawStyle parses the name of a draw style: one of "bars", "lines",
+// "circle", "waterfall" or "oscilloscope".
+func ParseDrawStyle(name string) (DrawStyle, error) {
+	style, ok := drawStyleNames[name]
+	if !ok {
+		return 0, fmt.Errorf("catnipgtk: unknown draw style %q", name)
+	}
+	return style, nil
+}
+
 // Display is a display of audio data.
 type Display interface {
 	gtk.Widgetter
+	Visualizer
+}
+
+// Visualizer draws audio data. Unlike Display, it is not necessarily a widget.
+type Visualizer interface {
 	AsOutput() DiscardableOutput
 	// SetSizes sets the sizes of the bars and spaces in the display.
 	SetSizes(bar, space float64)
@@ -41,6 +82,60 @@ type Display interface {
 	SetLineCap(lineCap cairo.LineCap)
 	// SetSamplingParams sets the sampling rate and size.
 	SetSamplingParams(rate float64, size int)
+	// SetWaterfallParams sets the number of frames kept by the waterfall, the
+	// direction it scrolls in and its color map.
+	SetWaterfallParams(history int, direction ScrollDirection, colorMap ColorMap)
+	// SetPeakCaps sets how long the peak caps are held for, how fast they
+	// fall and how thick they are. A thickness of 0 disables them.
+	SetPeakCaps(hold time.Duration, gravity, thickness float64)
+	// SetColors sets how the display is colored and the gradients used for
+	// the left and right channels.
+	SetColors(mode ColorMode, left, right Gradient)
+	// SetScaling sets how the bins are scaled to the height of the display.
+	SetScaling(scaling Scaling)
+	// SetFrequencyMapping sets the frequency mapping used by the analyzer.
+	SetFrequencyMapping(mapping FrequencyMapping)
+	// SetAxes sets whether the frequency and dB axes are drawn.
+	SetAxes(show bool)
+	// SetPauseWhenIdle sets whether the display stops drawing once the input
+	// has been silent for the ZeroThreshold of its Scaling.
+	SetPauseWhenIdle(pause bool)
+	// SetFrameRate sets the most frames drawn per second, or 0 to draw on
+	// every frame of the display.
+	SetFrameRate(fps int)
+	// SetInterpolation sets whether the display blends between the frames of
+	// the analyzer.
+	SetInterpolation(interpolate bool)
+}
+
+// Renderer is the renderer used to draw the spectrum.
+type Renderer string
+
+const (
+	// RendererCairo draws using Cairo. See CairoDisplay.
+	RendererCairo Renderer = "cairo"
+	// RendererSnapshot draws using GSK render nodes. See SnapshotDisplay.
+	RendererSnapshot Renderer = "snapshot"
+)
+
+// RendererDisplay is a Display that can switch between renderers.
+type RendererDisplay interface {
+	Display
+	SetRenderer(renderer Renderer)
+}
+
+// SampleOutput receives the time-domain samples of each buffer before they
+// are analyzed.
+type SampleOutput interface {
+	// WriteSamples is called with the samples of each channel. The buffers
+	// are only valid for the duration of the call.
+	WriteSamples(samples [][]float64)
+}
+
+// SampleDisplay is a Display that also wants the time-domain samples.
+type SampleDisplay interface {
+	Display
+	AsSampleOutput() SampleOutput
 }
 
 // DiscardableOutput extends processor.Output with a Discard method.
@@ -78,6 +173,124 @@ func (d *discardableOutput) Discard() {
 	atomic.StoreUint32(&d.discarded, 1)
 }
 
+// frameSlack is how much earlier than the frame rate allows a frame may still
+// be drawn, in µs, so that the jitter of the frame clock does not skip frames.
+const frameSlack = 1000
+
+// frameTicker draws a widget on the frames of its frame clock that changed
+// returns true for, at most at the frame rate if one is set. Once idle returns
+// true, it removes its tick callback so that the frame clock can stop, until
+// wake is called.
+type frameTicker struct {
+	widget  *gtk.Widget
+	draw    func()
+	changed func() bool
+	idle    func() bool
+	stopped atomic.Bool
+
+	// interval and last are only used on the main thread.
+	interval int64 // µs between frames, or 0 for every frame
+	last     int64 // frame time of the last frame in µs
+}
+
+func newFrameTicker(widget *gtk.Widget, draw func(), changed, idle func() bool) *frameTicker {
+	t := &frameTicker{
+		widget:  widget,
+		draw:    draw,
+		changed: changed,
+		idle:    idle,
+	}
+	t.start()
+	return t
+}
+
+func (t *frameTicker) start() {
+	t.widget.AddTickCallback(func(_ gtk.Widgetter, clock gdk.FrameClocker) bool {
+		return t.tick(gdk.BaseFrameClock(clock).FrameTime())
+	})
+}
+
+// tick handles the frame at the given frame time in µs. It returns whether to
+// keep ticking.
+func (t *frameTicker) tick(now int64) bool {
+	if now-t.last < t.interval-frameSlack {
+		return glib.SOURCE_CONTINUE
+	}
+
+	// Count from when this frame was due rather than from now, so that the
+	// frame rate averages out to the limit even if it is not a divisor of the
+	// refresh rate. Frames missed for longer than that are not made up for.
+	t.last += t.interval
+	if t.last < now-t.interval {
+		t.last = now
+	}
+
+	if t.changed() {
+		t.draw()
+	}
+
+	if t.idle() {
+		t.stopped.Store(true)
+		return glib.SOURCE_REMOVE
+	}
+	return glib.SOURCE_CONTINUE
+}
+
+// setFrameRate sets the most frames drawn per second, or 0 for no limit.
+func (t *frameTicker) setFrameRate(fps int) {
+	if t == nil {
+		return
+	}
+	t.interval = 0
+	if fps > 0 {
+		t.interval = int64(time.Second/time.Microsecond) / int64(fps)
+	}
+}
+
+// wake starts ticking again if the ticker has stopped. It may be called from
+// any goroutine, and is cheap enough to be called on every frame.
+func (t *frameTicker) wake() {
+	if t != nil && t.stopped.CompareAndSwap(true, false) {
+		glib.IdleAdd(t.start)
+	}
+}
+
+// cssBackground is a surface that holds the CSS background of the
+// .catnip-background class. Displays use it as the source for drawing so that
+// the colors can be styled using CSS.
+type cssBackground struct {
+	surface *cairo.Surface
+	context *cairo.Context
+	width   int
+	height  int
+}
+
+func (b *cssBackground) render(widget *gtk.Widget, cr *cairo.Context, width, height int) {
+	if b.width != width || b.height != height {
+		// Render the background onto the surface and use that as the source
+		// surface for our context.
+		b.surface = cr.Target().CreateSimilar(cairo.ContentColorAlpha, width, height)
+		b.context = cairo.Create(b.surface)
+		b.width = width
+		b.height = height
+	}
+
+	// Clear the background surface.
+	b.context.SetSourceRGBA(0, 0, 0, 0)
+	b.context.SetOperator(cairo.OperatorSource)
+	b.context.Paint()
+
+	// Draw the CSS background. We use the .catnip-background to get the
+	// CSS-drawn background, but we don't want to keep it around, so we
+	// remove the class after we're done.
+	styles := widget.StyleContext()
+	styles.Save()
+	defer styles.Restore()
+
+	styles.AddClass("catnip-background")
+	gtk.RenderBackground(styles, b.context, 0, 0, float64(width), float64(height))
+}
+
 func calculateBar(value, height float64) float64 {
 	bar := min(value, height)
 	return height - bar
@@ -2,213 +2,77 @@ package catnipgtk
 
 import (
 	"math"
-	"sync"
+	"unsafe"
 
 	"github.com/diamondburned/gotk4/pkg/cairo"
-	"github.com/diamondburned/gotk4/pkg/gdk/v4"
 	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
-	"github.com/noriah/catnip/input"
-
-	window "github.com/noriah/catnip/util"
 )
 
 // CairoDisplay is a display of audio data using the Cairo vector graphics
 // library.
 type CairoDisplay struct {
 	*gtk.DrawingArea
+	spectrum
 
-	window    *window.MovingWindow
-	drawStyle DrawStyle
-
-	background struct {
-		surface *cairo.Surface
-		context *cairo.Context
-		width   int
-		height  int
-	}
-
-	lock sync.Mutex
-
-	binsBuffer [][]float64
-	nchannels  int
-	peak       float64
-	scale      float64
-	zeroes     int
-
-	barWidth   float64
-	spaceWidth float64
-	binWidth   float64
-	lineCap    cairo.LineCap
-
-	width  int
-	height int
+	background cssBackground
 }
 
-var _ Display = (*CairoDisplay)(nil)
+var _ InspectableDisplay = (*CairoDisplay)(nil)
 
 // NewCairoDisplay creates a new display.
 func NewCairoDisplay(sampleRate float64, sampleSize int) *CairoDisplay {
 	d := &CairoDisplay{}
-	d.SetSizes(2, 3)
-	d.SetLineCap(cairo.LineCapRound)
-	d.SetDrawStyle(DrawBottomBars)
-	d.SetSamplingParams(sampleRate, sampleSize)
+	d.spectrum.init(sampleRate, sampleSize)
 
 	d.DrawingArea = gtk.NewDrawingArea()
 	d.DrawingArea.AddCSSClass("catnip-display")
 	d.DrawingArea.SetDrawFunc(d.draw)
-	d.DrawingArea.AddTickCallback(func(widget gtk.Widgetter, clock gdk.FrameClocker) (ok bool) {
-		base := gtk.BaseWidget(widget)
-		base.QueueDraw()
-		return glib.SOURCE_CONTINUE
-	})
+	d.ticker = newFrameTicker(&d.DrawingArea.Widget, d.DrawingArea.QueueDraw, d.frame, d.idle)
 
 	return d
 }
 
-// SetSizes sets the sizes of the bars and spaces in the display.
-func (d *CairoDisplay) SetSizes(bar, space float64) {
-	d.lock.Lock()
-	defer d.lock.Unlock()
-
-	d.barWidth = bar
-	d.spaceWidth = space
-	d.binWidth = bar + space
-}
-
-// SetDrawStyle sets the draw style.
-func (d *CairoDisplay) SetDrawStyle(style DrawStyle) {
-	d.drawStyle = style
-}
-
-// SetLineCap sets the line cap.
-func (d *CairoDisplay) SetLineCap(lineCap cairo.LineCap) {
-	d.lineCap = lineCap
-}
-
-// SetSamplingParams sets the sampling rate and size.
-func (d *CairoDisplay) SetSamplingParams(rate float64, size int) {
-	windowSize := ((int(ScalingWindow * rate)) / size) * 2
-
-	d.lock.Lock()
-	defer d.lock.Unlock()
-
-	d.window = window.NewMovingWindow(windowSize)
-}
-
 // QueueDraw queues a draw.
 func (d *CairoDisplay) QueueDraw() {
 	glib.IdleAdd(d.DrawingArea.QueueDraw)
 }
 
-// AsOutput returns the Display as a processor.Output.
-func (d *CairoDisplay) AsOutput() DiscardableOutput {
-	return WrapDiscardableOutput((*displayOutput)(d))
-}
-
-type displayOutput CairoDisplay
-
-// Write implements processor.Output.
-func (d *displayOutput) Write(bins [][]float64, nchannels int) error {
-	d.lock.Lock()
-	defer d.lock.Unlock()
-
-	if len(d.binsBuffer) != len(bins) || len(d.binsBuffer[0]) != len(bins[0]) {
-		d.binsBuffer = input.MakeBuffers(len(bins), len(bins[0]))
-	}
-	input.CopyBuffers(d.binsBuffer, bins)
-
-	nbins := (*CairoDisplay)(d).bins(nchannels)
-	var peak float64
-
-	for i := 0; i < nchannels; i++ {
-		for _, val := range bins[i][:nbins] {
-			if val > peak {
-				peak = val
-			}
-		}
-	}
-
-	d.peak = peak
-	d.scale = 1.0
-	d.nchannels = nchannels
-
-	if d.peak >= PeakThreshold {
-		// do some scaling if we are above the PeakThreshold
-		vMean, vSD := d.window.Update(d.peak)
-		if t := vMean + (2.0 * vSD); t > 1.0 {
-			d.scale = t
-		}
-
-		d.zeroes = 0
-	} else if d.zeroes < ZeroThreshold {
-		d.zeroes++
-	}
-
-	return nil
-}
-
-// Bins implements processor.Output.
-func (d *displayOutput) Bins(nchannels int) int {
-	d.lock.Lock()
-	defer d.lock.Unlock()
-
-	return (*CairoDisplay)(d).bins(nchannels)
-}
-
-func (d *CairoDisplay) bins(nchannels int) int {
-	return d.width / int(d.binWidth)
+func (d *CairoDisplay) draw(area *gtk.DrawingArea, cr *cairo.Context, width, height int) {
+	d.background.render(&area.Widget, cr, width, height)
+	d.spectrum.drawCairo(cr, d.background.surface, width, height)
+	d.spectrum.drawAxes(&area.Widget, cr, width, height)
 }
 
-func (d *CairoDisplay) draw(area *gtk.DrawingArea, cr *cairo.Context, width, height int) {
+// drawCairo draws the spectrum onto the given Cairo context. The background
+// surface is used as the source for ColorTheme.
+func (d *spectrum) drawCairo(cr *cairo.Context, background *cairo.Surface, width, height int) {
 	wf := float64(width)
 	hf := float64(height)
 
-	if d.background.width != width || d.background.height != height {
-		// Render the background onto the surface and use that as the source
-		// surface for our context.
-		d.background.surface = cr.Target().CreateSimilar(cairo.ContentColorAlpha, width, height)
-		d.background.context = cairo.Create(d.background.surface)
-		d.background.width = width
-		d.background.height = height
-	}
-
-	// Clear the background surface.
-	d.background.context.SetSourceRGBA(0, 0, 0, 0)
-	d.background.context.SetOperator(cairo.OperatorSource)
-	d.background.context.Paint()
-
-	// Draw the CSS background. We use the .catnip-background to get the
-	// CSS-drawn background, but we don't want to keep it around, so we
-	// remove the class after we're done.
-	styles := area.StyleContext()
-	styles.Save()
-	defer styles.Restore()
-
-	styles.AddClass("catnip-background")
-	gtk.RenderBackground(styles, d.background.context, 0, 0, wf, hf)
+	d.lock.Lock()
+	defer d.lock.Unlock()
 
 	cr.SetAntialias(cairo.AntialiasFast)
 	cr.SetLineWidth(d.barWidth)
 	cr.SetLineCap(d.lineCap)
-	cr.SetSourceSurface(d.background.surface, 0, 0)
-
-	d.lock.Lock()
-	defer d.lock.Unlock()
 
 	d.width = width
 	d.height = height
 
 	switch d.drawStyle {
 	case DrawBottomBars:
-		d.drawBottomBars(cr, wf, hf)
+		d.drawBottomBars(cr, background, wf, hf)
 	case DrawLines:
-		d.drawLines(cr, wf, hf)
+		d.drawLines(cr, background, wf, hf)
+	case DrawCircle:
+		d.drawCircle(cr, background, wf, hf)
+	case DrawWaterfall:
+		d.drawWaterfall(cr, width, height)
 	}
 }
 
-func (d *CairoDisplay) drawBottomBars(cr *cairo.Context, wf, hf float64) {
+func (d *spectrum) drawBottomBars(cr *cairo.Context, background *cairo.Surface, wf, hf float64) {
 	bins := d.binsBuffer
 
 	delta := 1
@@ -221,11 +85,22 @@ func (d *CairoDisplay) drawBottomBars(cr *cairo.Context, wf, hf float64) {
 	xBin := 0
 	xCol := (d.binWidth)/2 + (wf-xColMax)/2
 
-	for _, chBins := range bins {
+	for ch, chBins := range bins {
+		d.colors.setSource(cr, ch, background, wf, hf)
+
 		for xBin < nbars && xBin >= 0 && xCol < xColMax {
+			d.colors.setMagnitude(cr, ch, min(chBins[xBin]/d.scale, 1))
+
 			stop := calculateBar(chBins[xBin]*scale, hf)
 			d.drawBar(cr, xCol, hf, stop)
 
+			if peak, ok := d.peaks.at(ch, xBin); ok {
+				d.colors.setMagnitude(cr, ch, peak)
+
+				top := calculateBar(peak*hf, hf)
+				d.drawBar(cr, xCol, top, top-d.peaks.thickness)
+			}
+
 			xCol += d.binWidth
 			xBin += delta
 		}
@@ -235,13 +110,13 @@ func (d *CairoDisplay) drawBottomBars(cr *cairo.Context, wf, hf float64) {
 	}
 }
 
-func (d *CairoDisplay) drawBar(cr *cairo.Context, xCol, to, from float64) {
+func (d *spectrum) drawBar(cr *cairo.Context, xCol, to, from float64) {
 	cr.MoveTo(xCol, from)
 	cr.LineTo(xCol, to)
 	cr.Stroke()
 }
 
-func (d *CairoDisplay) drawLines(cr *cairo.Context, wf, hf float64) {
+func (d *spectrum) drawLines(cr *cairo.Context, background *cairo.Surface, wf, hf float64) {
 	bins := d.binsBuffer
 	scale := hf / d.scale
 	nbars := d.bins(d.nchannels)
@@ -262,7 +137,16 @@ func (d *CairoDisplay) drawLines(cr *cairo.Context, wf, hf float64) {
 	var bar int
 	first := true
 
-	for _, ch := range bins {
+	for chIx, ch := range bins {
+		if !first {
+			// Stroke what we have so far so that each channel can have its
+			// own colors, then continue from where we left off.
+			x, y := cr.CurrentPoint()
+			cr.Stroke()
+			cr.MoveTo(x, y)
+		}
+		d.colors.setSource(cr, chIx, background, wf, hf)
+
 		// If we're iterating backwards, then check the lower bound, or
 		// if we're iterating forwards, then check the upper bound.
 		// Ignore the last bar for the same reason above.
@@ -294,6 +178,162 @@ func (d *CairoDisplay) drawLines(cr *cairo.Context, wf, hf float64) {
 	cr.Stroke()
 }
 
+func (d *spectrum) drawCircle(cr *cairo.Context, background *cairo.Surface, wf, hf float64) {
+	bins := d.binsBuffer
+	nbars := d.bins(d.nchannels)
+	if nbars <= 0 || d.nchannels <= 0 {
+		return
+	}
+
+	cx := wf / 2
+	cy := hf / 2
+
+	radius := d.circleRadius(wf, hf)
+	// Bars may extend from the ring to the edge of the display.
+	maxLength := min(wf, hf)/2 - radius
+	scale := maxLength / d.scale
+
+	// Each channel gets an equal share of the ring. Angles start from the top
+	// of the ring, and every other channel goes the opposite way so that
+	// stereo channels are mirrored across the vertical axis.
+	step := 2 * math.Pi / float64(d.nchannels*nbars)
+
+	for ch, chBins := range bins[:min(len(bins), d.nchannels)] {
+		d.colors.setSource(cr, ch, background, wf, hf)
+
+		direction := 1.0
+		if ch%2 == 1 {
+			direction = -1.0
+		}
+
+		for bar := 0; bar < nbars && bar < len(chBins); bar++ {
+			angle := -math.Pi/2 + direction*(float64(bar)+0.5)*step
+			length := min(chBins[bar]*scale, maxLength)
+			d.colors.setMagnitude(cr, ch, length/maxLength)
+
+			cos := math.Cos(angle)
+			sin := math.Sin(angle)
+
+			cr.MoveTo(cx+radius*cos, cy+radius*sin)
+			cr.LineTo(cx+(radius+length)*cos, cy+(radius+length)*sin)
+			cr.Stroke()
+
+			if peak, ok := d.peaks.at(ch, bar); ok {
+				d.colors.setMagnitude(cr, ch, peak)
+
+				from := radius + peak*maxLength
+				to := from + d.peaks.thickness
+
+				cr.MoveTo(cx+from*cos, cy+from*sin)
+				cr.LineTo(cx+to*cos, cy+to*sin)
+				cr.Stroke()
+			}
+		}
+	}
+}
+
+func (d *spectrum) drawWaterfall(cr *cairo.Context, width, height int) {
+	w := &d.waterfall
+
+	history := w.history.len()
+	rowHeight := max(1, int(math.Ceil(float64(height)/float64(history))))
+
+	if w.surface == nil || w.surface.Width() != width || w.rowHeight != rowHeight {
+		// Every frame takes up a row of rowHeight pixels on the surface. Since
+		// the surface is a ring buffer like the history, we have to repaint
+		// everything that is still in the history.
+		w.surface = cairo.CreateImageSurface(cairo.FormatARGB32, width, history*rowHeight)
+		w.rowHeight = rowHeight
+		w.painted = max(0, w.history.count-history)
+	}
+
+	if w.painted < w.history.count {
+		w.surface.Flush()
+		for ; w.painted < w.history.count; w.painted++ {
+			d.paintWaterfallFrame(w.painted)
+		}
+		w.surface.MarkDirty()
+	}
+
+	if w.history.count == 0 {
+		return
+	}
+
+	// Find the row that should be at the top of the display, then blit the
+	// rows after it followed by the rows before it.
+	var top int
+	var y0 float64
+
+	newest := d.waterfallRow(w.history.count - 1)
+	switch w.direction {
+	case ScrollUp:
+		top = (newest + 1) % history
+		y0 = float64(height - history*rowHeight)
+	default:
+		top = newest
+		y0 = 0
+	}
+
+	split := y0 + float64((history-top)*rowHeight)
+
+	cr.SetSourceSurface(w.surface, 0, y0-float64(top*rowHeight))
+	cr.Rectangle(0, y0, float64(width), split-y0)
+	cr.Fill()
+
+	cr.SetSourceSurface(w.surface, 0, split)
+	cr.Rectangle(0, split, float64(width), float64(top*rowHeight))
+	cr.Fill()
+}
+
+// waterfallRow returns the row on the waterfall surface that the nth frame is
+// painted on.
+func (d *spectrum) waterfallRow(n int) int {
+	history := d.waterfall.history.len()
+	switch d.waterfall.direction {
+	case ScrollUp:
+		return n % history
+	default:
+		// Newer frames go on top, so rows are laid out backwards.
+		return history - 1 - n%history
+	}
+}
+
+func (d *spectrum) paintWaterfallFrame(n int) {
+	w := &d.waterfall
+
+	frame := w.history.frame(n)
+	if len(frame) == 0 {
+		return
+	}
+
+	data := w.surface.Data()
+	stride := w.surface.Stride()
+	width := w.surface.Width()
+	y := d.waterfallRow(n) * w.rowHeight
+
+	// Stretch the frame across the width of the surface.
+	row := data[y*stride : (y+1)*stride]
+	for x := 0; x < width; x++ {
+		r, g, b := w.colorMap.At(frame[x*len(frame)/width])
+		setPixel(row, x, r, g, b)
+	}
+
+	// Copy the first line to the rest of the row.
+	for i := 1; i < w.rowHeight; i++ {
+		copy(data[(y+i)*stride:(y+i+1)*stride], row)
+	}
+}
+
+// setPixel sets the xth pixel in an opaque ARGB32 line.
+func setPixel(line []byte, x int, r, g, b float64) {
+	pixel := uint32(0xFF)<<24 |
+		uint32(r*0xFF)<<16 |
+		uint32(g*0xFF)<<8 |
+		uint32(b*0xFF)
+	// Cairo stores each pixel as a native-endian uint32.
+	*(*uint32)(unsafe.Pointer(&line[x*4])) = pixel
+}
+
 // quadCurve draws a quadratic bezier curve into the given Cairo context.
 func quadCurve(cr *cairo.Context, p1x, p1y, p2x, p2y float64) {
 	p0x, p0y := cr.CurrentPoint()
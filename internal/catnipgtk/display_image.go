@@ -0,0 +1,76 @@
+package catnipgtk
+
+import (
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+)
+
+// ImageVisualizer draws the spectrum onto Cairo image surfaces. It does not
+// need a widget or a running GTK main loop, so it can be used for rendering
+// frames offscreen. DrawOscilloscope is not supported and draws nothing.
+type ImageVisualizer struct {
+	spectrum
+
+	background Color
+	foreground Color
+	fill       *cairo.Surface
+}
+
+var _ Visualizer = (*ImageVisualizer)(nil)
+
+// NewImageVisualizer creates a new image visualizer. It draws white on black
+// until SetPalette is called.
+func NewImageVisualizer(sampleRate float64, sampleSize int) *ImageVisualizer {
+	v := &ImageVisualizer{
+		background: Color{0, 0, 0, 1},
+		foreground: Color{1, 1, 1, 1},
+	}
+	v.spectrum.init(sampleRate, sampleSize)
+	return v
+}
+
+// SetPalette sets the background color and the color used in place of the
+// theme's foreground color for ColorTheme.
+func (v *ImageVisualizer) SetPalette(background, foreground Color) {
+	v.background = background
+	v.foreground = foreground
+	v.fill = nil
+}
+
+// SetClock sets the function used to get the current time. Rendering offline
+// uses it to step through time at its own pace.
+func (v *ImageVisualizer) SetClock(now func() time.Time) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+
+	v.now = now
+}
+
+// Render draws the current frame onto a new image surface of the given size.
+func (v *ImageVisualizer) Render(width, height int) *cairo.Surface {
+	if v.fill == nil || v.fill.Width() != width || v.fill.Height() != height {
+		// This stands in for the CSS background that CairoDisplay would use.
+		v.fill = cairo.CreateImageSurface(cairo.FormatARGB32, width, height)
+		fillSurface(v.fill, v.foreground)
+	}
+
+	surface := cairo.CreateImageSurface(cairo.FormatARGB32, width, height)
+	fillSurface(surface, v.background)
+
+	// Blend the bins at the time of the clock if interpolating.
+	v.spectrum.frame()
+
+	cr := cairo.Create(surface)
+	v.spectrum.drawCairo(cr, v.fill, width, height)
+	surface.Flush()
+
+	return surface
+}
+
+func fillSurface(surface *cairo.Surface, color Color) {
+	cr := cairo.Create(surface)
+	cr.SetSourceRGBA(color.R, color.G, color.B, color.A)
+	cr.SetOperator(cairo.OperatorSource)
+	cr.Paint()
+}
@@ -0,0 +1,275 @@
+package catnipgtk
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/noriah/catnip/input"
+)
+
+// OscilloscopeDisplay is a display that draws the time-domain waveform of the
+// input instead of its spectrum.
+type OscilloscopeDisplay struct {
+	*gtk.DrawingArea
+
+	background cssBackground
+	ticker     *frameTicker
+
+	lock sync.Mutex
+
+	samples   [][]float64
+	colors    strokeColors
+	lineWidth float64
+	lineCap   cairo.LineCap
+
+	dirty         bool // true if changed since the last frame
+	zeroes        int  // number of silent buffers in a row
+	zeroThreshold int
+	pauseWhenIdle bool
+}
+
+// oscilloscopeSilence is the level below which a buffer is treated as silent.
+const oscilloscopeSilence = 1e-4
+
+var _ SampleDisplay = (*OscilloscopeDisplay)(nil)
+
+// NewOscilloscopeDisplay creates a new oscilloscope display.
+func NewOscilloscopeDisplay() *OscilloscopeDisplay {
+	d := &OscilloscopeDisplay{zeroThreshold: ZeroThreshold}
+	d.SetSizes(2, 3)
+	d.SetLineCap(cairo.LineCapRound)
+
+	d.DrawingArea = gtk.NewDrawingArea()
+	d.DrawingArea.AddCSSClass("catnip-display")
+	d.DrawingArea.AddCSSClass("catnip-oscilloscope")
+	d.DrawingArea.SetDrawFunc(d.draw)
+	d.ticker = newFrameTicker(&d.DrawingArea.Widget, d.DrawingArea.QueueDraw, d.frame, d.idle)
+
+	return d
+}
+
+// SetSizes sets the width of the trace. The space is ignored.
+func (d *OscilloscopeDisplay) SetSizes(bar, space float64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.lineWidth = bar
+	d.invalidate()
+}
+
+// SetDrawStyle does nothing, since the oscilloscope only has one style.
+func (d *OscilloscopeDisplay) SetDrawStyle(style DrawStyle) {}
+
+// SetLineCap sets the line cap.
+func (d *OscilloscopeDisplay) SetLineCap(lineCap cairo.LineCap) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.lineCap = lineCap
+	d.invalidate()
+}
+
+// SetSamplingParams does nothing, since the oscilloscope draws whatever buffer
+// it is given.
+func (d *OscilloscopeDisplay) SetSamplingParams(rate float64, size int) {}
+
+// SetWaterfallParams does nothing.
+func (d *OscilloscopeDisplay) SetWaterfallParams(history int, direction ScrollDirection, colorMap ColorMap) {
+}
+
+// SetPeakCaps does nothing.
+func (d *OscilloscopeDisplay) SetPeakCaps(hold time.Duration, gravity, thickness float64) {}
+
+// SetScaling only uses the ZeroThreshold, since the samples are always in
+// [-1, 1].
+func (d *OscilloscopeDisplay) SetScaling(scaling Scaling) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.zeroThreshold = scaling.ZeroThreshold
+	d.invalidate()
+}
+
+// SetFrequencyMapping does nothing.
+func (d *OscilloscopeDisplay) SetFrequencyMapping(mapping FrequencyMapping) {}
+
+// SetAxes does nothing, since the oscilloscope has no frequency axis.
+func (d *OscilloscopeDisplay) SetAxes(show bool) {}
+
+// SetColors sets how the traces are colored.
+func (d *OscilloscopeDisplay) SetColors(mode ColorMode, left, right Gradient) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.colors = strokeColors{mode, left, right}
+	d.invalidate()
+}
+
+// SetPauseWhenIdle sets whether the display stops drawing while the input is
+// silent.
+func (d *OscilloscopeDisplay) SetPauseWhenIdle(pause bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.pauseWhenIdle = pause
+	d.invalidate()
+}
+
+// SetFrameRate sets the most frames drawn per second, or 0 to draw on every
+// frame of the display. It must be called on the main thread.
+func (d *OscilloscopeDisplay) SetFrameRate(fps int) {
+	d.ticker.setFrameRate(fps)
+}
+
+// SetInterpolation does nothing, since the samples of a buffer are drawn as
+// they are.
+func (d *OscilloscopeDisplay) SetInterpolation(interpolate bool) {}
+
+// invalidate makes the display draw again on the next frame. The lock must be
+// held.
+func (d *OscilloscopeDisplay) invalidate() {
+	d.dirty = true
+	d.ticker.wake()
+}
+
+// frame returns whether the samples or the settings changed since the last
+// frame.
+func (d *OscilloscopeDisplay) frame() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	changed := d.dirty
+	d.dirty = false
+	return changed
+}
+
+// idle returns whether the input has been silent for long enough to stop
+// drawing.
+func (d *OscilloscopeDisplay) idle() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.idleLocked()
+}
+
+func (d *OscilloscopeDisplay) idleLocked() bool {
+	return d.pauseWhenIdle && d.zeroes > 0 && d.zeroes >= d.zeroThreshold
+}
+
+// AsOutput returns the display as a processor.Output. The oscilloscope does
+// not use the analyzed bins, so it only asks for a single bin.
+func (d *OscilloscopeDisplay) AsOutput() DiscardableOutput {
+	return WrapDiscardableOutput(oscilloscopeOutput{})
+}
+
+type oscilloscopeOutput struct{}
+
+func (oscilloscopeOutput) Bins(nchannels int) int                      { return 1 }
+func (oscilloscopeOutput) Write(bins [][]float64, nchannels int) error { return nil }
+
+// AsSampleOutput returns the display as a SampleOutput.
+func (d *OscilloscopeDisplay) AsSampleOutput() SampleOutput {
+	return (*oscilloscopeSampleOutput)(d)
+}
+
+type oscilloscopeSampleOutput OscilloscopeDisplay
+
+// WriteSamples implements SampleOutput.
+func (d *oscilloscopeSampleOutput) WriteSamples(samples [][]float64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.samples) != len(samples) || len(d.samples[0]) != len(samples[0]) {
+		d.samples = input.MakeBuffers(len(samples), len(samples[0]))
+	}
+	input.CopyBuffers(d.samples, samples)
+	d.dirty = true
+
+	if isSilent(samples) {
+		if d.zeroes < math.MaxInt32 {
+			d.zeroes++
+		}
+	} else {
+		d.zeroes = 0
+	}
+
+	if !(*OscilloscopeDisplay)(d).idleLocked() {
+		d.ticker.wake()
+	}
+}
+
+func isSilent(samples [][]float64) bool {
+	for _, ch := range samples {
+		for _, sample := range ch {
+			if math.Abs(sample) >= oscilloscopeSilence {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (d *OscilloscopeDisplay) draw(area *gtk.DrawingArea, cr *cairo.Context, width, height int) {
+	wf := float64(width)
+	hf := float64(height)
+
+	d.background.render(&area.Widget, cr, width, height)
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	cr.SetAntialias(cairo.AntialiasFast)
+	cr.SetLineWidth(d.lineWidth)
+	cr.SetLineCap(d.lineCap)
+
+	if len(d.samples) == 0 {
+		return
+	}
+
+	// Give each channel its own lane.
+	laneHeight := hf / float64(len(d.samples))
+
+	for ch, samples := range d.samples {
+		// Only draw half of the buffer, starting from the trigger point, so
+		// that the waveform stays still and is always the same length.
+		n := len(samples) / 2
+		if n < 2 {
+			continue
+		}
+
+		start := triggerPoint(samples[:n])
+		trace := samples[start : start+n]
+
+		center := laneHeight*float64(ch) + laneHeight/2
+		amplitude := laneHeight / 2
+		step := wf / float64(n-1)
+
+		d.colors.setSource(cr, ch, d.background.surface, wf, hf)
+
+		for i, sample := range trace {
+			x := float64(i) * step
+			y := center - max(-1, min(sample, 1))*amplitude
+			if i == 0 {
+				cr.MoveTo(x, y)
+			} else {
+				cr.LineTo(x, y)
+			}
+		}
+
+		cr.Stroke()
+	}
+}
+
+// triggerPoint returns the index of the first rising zero crossing in the
+// given samples, or 0 if there is none.
+func triggerPoint(samples []float64) int {
+	for i := 1; i < len(samples); i++ {
+		if samples[i-1] < 0 && samples[i] >= 0 {
+			return i
+		}
+	}
+	return 0
+}
@@ -0,0 +1,270 @@
+package catnipgtk
+
+import (
+	"math"
+	"unsafe"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/core/gextras"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/graphene"
+	"github.com/diamondburned/gotk4/pkg/gsk/v4"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// SnapshotDisplay is a display of audio data using GSK render nodes. Unlike
+// CairoDisplay, the bars are drawn as color and texture nodes, which GTK's
+// renderer can batch and cache. Draw styles other than DrawBottomBars are
+// drawn inside a Cairo node.
+type SnapshotDisplay struct {
+	*gtk.Picture
+	spectrum
+
+	background cssBackground
+	// fills caches the gradient textures of each channel.
+	fills [2]struct {
+		texture gdk.Texturer
+		colors  strokeColors
+		width   int
+		height  int
+	}
+	// drawn is the size of the last paintable.
+	drawn struct {
+		width  int
+		height int
+	}
+}
+
+var _ InspectableDisplay = (*SnapshotDisplay)(nil)
+
+// NewSnapshotDisplay creates a new display.
+func NewSnapshotDisplay(sampleRate float64, sampleSize int) *SnapshotDisplay {
+	d := &SnapshotDisplay{}
+	d.spectrum.init(sampleRate, sampleSize)
+
+	d.Picture = gtk.NewPicture()
+	d.Picture.AddCSSClass("catnip-display")
+	d.Picture.SetCanShrink(true)
+	d.Picture.SetKeepAspectRatio(false)
+	d.Picture.SetHExpand(true)
+	d.Picture.SetVExpand(true)
+	d.ticker = newFrameTicker(&d.Picture.Widget, d.update, d.changed, d.idle)
+
+	return d
+}
+
+// changed returns whether the spectrum or the size of the picture changed
+// since the last update. Unlike a DrawingArea, the picture is not drawn again
+// by GTK when it is resized.
+func (d *SnapshotDisplay) changed() bool {
+	changed := d.frame()
+	return changed || d.Picture.Width() != d.drawn.width || d.Picture.Height() != d.drawn.height
+}
+
+// update snapshots the spectrum into a new paintable for the picture.
+func (d *SnapshotDisplay) update() {
+	width := d.Picture.Width()
+	height := d.Picture.Height()
+	d.drawn.width = width
+	d.drawn.height = height
+	if width <= 0 || height <= 0 {
+		return
+	}
+
+	snapshot := gtk.NewSnapshot()
+	d.snapshot(snapshot, width, height)
+
+	size := graphene.NewSizeAlloc().Init(float32(width), float32(height))
+	d.Picture.SetPaintable(snapshot.ToPaintable(size))
+}
+
+func (d *SnapshotDisplay) snapshot(snapshot *gtk.Snapshot, width, height int) {
+	d.lock.Lock()
+	style := d.drawStyle
+	axes := d.axes
+	d.lock.Unlock()
+
+	if axes {
+		// Draw the axes over everything else once we're done.
+		defer func() {
+			bounds := graphene.RectAlloc().Init(0, 0, float32(width), float32(height))
+			d.spectrum.drawAxes(&d.Picture.Widget, snapshot.AppendCairo(bounds), width, height)
+		}()
+	}
+
+	if style != DrawBottomBars {
+		bounds := graphene.RectAlloc().Init(0, 0, float32(width), float32(height))
+		cr := snapshot.AppendCairo(bounds)
+
+		d.background.render(&d.Picture.Widget, cr, width, height)
+		d.spectrum.drawCairo(cr, d.background.surface, width, height)
+		return
+	}
+
+	// Render the background using the .catnip-background class, the same way
+	// cssBackground does.
+	styles := d.Picture.StyleContext()
+	styles.Save()
+	defer styles.Restore()
+	styles.AddClass("catnip-background")
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.width = width
+	d.height = height
+
+	d.snapshotBottomBars(snapshot, styles, float64(width), float64(height))
+}
+
+func (d *SnapshotDisplay) snapshotBottomBars(snapshot *gtk.Snapshot, styles *gtk.StyleContext, wf, hf float64) {
+	bins := d.binsBuffer
+
+	delta := 1
+	scale := hf / d.scale
+	nbars := d.bins(d.nchannels)
+
+	// Round up the width so we don't draw a partial bar.
+	xColMax := math.Round(wf/d.binWidth) * d.binWidth
+
+	xBin := 0
+	xCol := (d.binWidth)/2 + (wf-xColMax)/2
+
+	for ch, chBins := range bins {
+		fill := d.fillTexture(ch, wf, hf)
+
+		for xBin < nbars && xBin >= 0 && xCol < xColMax {
+			stop := calculateBar(chBins[xBin]*scale, hf)
+			d.snapshotBar(snapshot, styles, fill, ch, min(chBins[xBin]/d.scale, 1), xCol, hf, stop, wf, hf)
+
+			if peak, ok := d.peaks.at(ch, xBin); ok {
+				top := calculateBar(peak*hf, hf)
+				d.snapshotBar(snapshot, styles, fill, ch, peak, xCol, top, top-d.peaks.thickness, wf, hf)
+			}
+
+			xCol += d.binWidth
+			xBin += delta
+		}
+
+		delta = -delta
+		xBin += delta // ensure xBin is not out of bounds first.
+	}
+}
+
+// snapshotBar appends a bar that goes from the from Y coordinate to the to Y
+// coordinate. Like the Cairo display, non-butt line caps extend the bar by
+// half the bar width on both ends, and round caps are clipped into a half
+// circle.
+func (d *SnapshotDisplay) snapshotBar(
+	snapshot *gtk.Snapshot, styles *gtk.StyleContext, fill gdk.Texturer,
+	ch int, magnitude, xCol, to, from, wf, hf float64) {
+
+	var extent float64
+	switch d.lineCap {
+	case cairo.LineCapSquare:
+		if to == from {
+			// Cairo cannot tell which way an empty line faces, so it draws
+			// no caps at all.
+			return
+		}
+		extent = d.barWidth / 2
+	case cairo.LineCapRound:
+		// An empty line is still drawn as a dot.
+		extent = d.barWidth / 2
+	}
+
+	bounds := graphene.RectAlloc().Init(
+		float32(xCol-d.barWidth/2),
+		float32(from-extent),
+		float32(d.barWidth),
+		float32(to-from+2*extent),
+	)
+
+	if d.lineCap == cairo.LineCapRound {
+		var outline roundedRect
+		snapshot.PushRoundedClip(outline.init(bounds, float32(d.barWidth/2)))
+		defer snapshot.Pop()
+	}
+
+	gradient := d.colors.gradient(ch)
+	if gradient.Len() == 0 {
+		d.snapshotBackground(snapshot, styles, bounds, wf, hf)
+		return
+	}
+
+	switch d.colors.mode {
+	case ColorSolid:
+		appendColor(snapshot, gradient.stops[0].Color, bounds)
+	case ColorMagnitude:
+		appendColor(snapshot, gradient.At(magnitude), bounds)
+	case ColorVertical, ColorHorizontal:
+		snapshot.PushClip(bounds)
+		snapshot.AppendTexture(fill, graphene.RectAlloc().Init(0, 0, float32(wf), float32(hf)))
+		snapshot.Pop()
+	default:
+		d.snapshotBackground(snapshot, styles, bounds, wf, hf)
+	}
+}
+
+func (d *SnapshotDisplay) snapshotBackground(snapshot *gtk.Snapshot, styles *gtk.StyleContext, bounds *graphene.Rect, wf, hf float64) {
+	snapshot.PushClip(bounds)
+	snapshot.RenderBackground(styles, 0, 0, wf, hf)
+	snapshot.Pop()
+}
+
+// fillTexture returns the texture holding the gradient of the given channel,
+// or nil if the color mode does not use one. The texture is only re-rendered
+// if the size or the colors change.
+func (d *SnapshotDisplay) fillTexture(ch int, wf, hf float64) gdk.Texturer {
+	if d.colors.mode != ColorVertical && d.colors.mode != ColorHorizontal {
+		return nil
+	}
+	if d.colors.gradient(ch).Len() == 0 {
+		return nil
+	}
+
+	fill := &d.fills[ch%len(d.fills)]
+	width := int(wf)
+	height := int(hf)
+
+	if fill.texture != nil && fill.colors == d.colors && fill.width == width && fill.height == height {
+		return fill.texture
+	}
+
+	surface := cairo.CreateImageSurface(cairo.FormatARGB32, width, height)
+	cr := cairo.Create(surface)
+	d.colors.setSource(cr, ch, nil, wf, hf)
+	cr.Paint()
+	surface.Flush()
+
+	// Cairo's ARGB32 is premultiplied BGRA on little-endian machines.
+	bytes := glib.NewBytes(append([]byte(nil), surface.Data()...))
+	fill.texture = gdk.NewMemoryTexture(width, height, gdk.MemoryB8G8R8A8Premultiplied, bytes, uint(surface.Stride()))
+	fill.colors = d.colors
+	fill.width = width
+	fill.height = height
+
+	return fill.texture
+}
+
+// roundedRect has the memory layout of a GskRoundedRect: the bounds followed
+// by the size of each corner. gotk4 cannot allocate one, so GTK initializes
+// this one in place, and copies it when it is used.
+type roundedRect struct {
+	bounds  [4]float32
+	corners [4][2]float32
+}
+
+// init sets all corners of r to the same radius and returns it as a
+// gsk.RoundedRect, which is only valid while r is.
+func (r *roundedRect) init(bounds *graphene.Rect, radius float32) *gsk.RoundedRect {
+	rect := (*gsk.RoundedRect)(gextras.NewStructNative(unsafe.Pointer(r)))
+	rect.InitFromRect(bounds, radius)
+	return rect
+}
+
+func appendColor(snapshot *gtk.Snapshot, c Color, bounds *graphene.Rect) {
+	rgba := gdk.NewRGBA(float32(c.R), float32(c.G), float32(c.B), float32(c.A))
+	snapshot.AppendColor(&rgba, bounds)
+}
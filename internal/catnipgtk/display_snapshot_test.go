@@ -0,0 +1,188 @@
+package catnipgtk
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gsk/v4"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+const (
+	parityWidth  = 128
+	parityHeight = 100
+	// parityTolerance is how far apart two channels of a pixel may be.
+	parityTolerance = 4
+	// parityEdgeTolerance is how far apart two channels of a pixel on an
+	// antialiased edge may be. The Cairo display draws with fast antialiasing,
+	// while the render nodes are drawn with the default.
+	parityEdgeTolerance = 64
+)
+
+// parityCSS styles the displays for ColorTheme.
+const parityCSS = `
+.catnip-background {
+	background: linear-gradient(to right, #e66100, #2ec27e);
+}
+`
+
+func TestSnapshotCairoParity(t *testing.T) {
+	if !gtk.InitCheck() {
+		t.Skip("no display to initialize GTK on")
+	}
+
+	css := gtk.NewCSSProvider()
+	css.LoadFromData(parityCSS)
+	display := gdk.DisplayGetDefault()
+	gtk.StyleContextAddProviderForDisplay(display, css, gtk.STYLE_PROVIDER_PRIORITY_APPLICATION)
+	t.Cleanup(func() { gtk.StyleContextRemoveProviderForDisplay(display, css) })
+
+	gradient := NewGradient(
+		ColorStop{0, MustParseColor("#3584e4")},
+		ColorStop{1, MustParseColor("#c061cb")},
+	)
+
+	tests := []struct {
+		style   DrawStyle
+		mode    ColorMode
+		lineCap cairo.LineCap
+		peaks   bool
+	}{
+		// The default config.
+		{DrawBottomBars, ColorTheme, cairo.LineCapRound, false},
+		{DrawBottomBars, ColorTheme, cairo.LineCapRound, true},
+		{DrawBottomBars, ColorTheme, cairo.LineCapButt, false},
+		{DrawBottomBars, ColorSolid, cairo.LineCapButt, false},
+		{DrawBottomBars, ColorSolid, cairo.LineCapSquare, false},
+		{DrawBottomBars, ColorSolid, cairo.LineCapRound, false},
+		{DrawBottomBars, ColorVertical, cairo.LineCapRound, true},
+		{DrawBottomBars, ColorSolid, cairo.LineCapButt, true},
+		{DrawBottomBars, ColorMagnitude, cairo.LineCapButt, false},
+		{DrawBottomBars, ColorVertical, cairo.LineCapButt, false},
+		{DrawBottomBars, ColorHorizontal, cairo.LineCapButt, true},
+		{DrawLines, ColorSolid, cairo.LineCapButt, false},
+		{DrawLines, ColorVertical, cairo.LineCapRound, false},
+		{DrawLines, ColorTheme, cairo.LineCapRound, false},
+	}
+
+	for _, test := range tests {
+		name := fmt.Sprintf("%d/%s/%d/peaks=%t", test.style, test.mode, test.lineCap, test.peaks)
+		t.Run(name, func(t *testing.T) {
+			cairoDisplay := NewCairoDisplay(44100, 1024)
+			snapshotDisplay := NewSnapshotDisplay(44100, 1024)
+
+			for _, s := range []*spectrum{&cairoDisplay.spectrum, &snapshotDisplay.spectrum} {
+				// Bars 2px wide on a 4px grid land on whole pixels, so both
+				// renderers should agree up to rounding.
+				s.SetSizes(2, 2)
+				s.SetDrawStyle(test.style)
+				s.SetLineCap(test.lineCap)
+				s.SetColors(test.mode, gradient, gradient)
+				s.SetScaling(Scaling{
+					Window:        ScalingWindow,
+					PeakThreshold: PeakThreshold,
+					ZeroThreshold: ZeroThreshold,
+				})
+				if test.peaks {
+					s.SetPeakCaps(time.Second, 1, 2)
+				}
+				writeParityBins(s)
+			}
+
+			want := renderCairo(cairoDisplay)
+			got := renderSnapshot(snapshotDisplay)
+			compareSurfaces(t, want, got)
+		})
+	}
+}
+
+// writeParityBins writes the same stereo spectrum to s. The size is set first
+// so that the spectrum knows how many bins fit.
+func writeParityBins(s *spectrum) {
+	s.lock.Lock()
+	s.width = parityWidth
+	s.height = parityHeight
+	s.lock.Unlock()
+
+	output := s.AsOutput()
+	nbins := output.Bins(2)
+
+	bins := [][]float64{make([]float64, nbins), make([]float64, nbins)}
+	for i := 0; i < nbins; i++ {
+		bins[0][i] = float64((i*7)%10) / 10
+		bins[1][i] = float64((i*3)%10) / 10
+	}
+
+	output.Write(bins, 2)
+}
+
+func renderCairo(d *CairoDisplay) *cairo.Surface {
+	surface := cairo.CreateImageSurface(cairo.FormatARGB32, parityWidth, parityHeight)
+	cr := cairo.Create(surface)
+	d.draw(d.DrawingArea, cr, parityWidth, parityHeight)
+	surface.Flush()
+	return surface
+}
+
+func renderSnapshot(d *SnapshotDisplay) *cairo.Surface {
+	snapshot := gtk.NewSnapshot()
+	d.snapshot(snapshot, parityWidth, parityHeight)
+
+	surface := cairo.CreateImageSurface(cairo.FormatARGB32, parityWidth, parityHeight)
+	cr := cairo.Create(surface)
+	if node := snapshot.ToNode(); node != nil {
+		gsk.BaseRenderNode(node).Draw(cr)
+	}
+	surface.Flush()
+	return surface
+}
+
+func compareSurfaces(t *testing.T, want, got *cairo.Surface) {
+	t.Helper()
+
+	wantData := want.Data()
+	gotData := got.Data()
+	stride := want.Stride()
+
+	var painted, mismatched int
+	for y := 0; y < parityHeight; y++ {
+		for x := 0; x < parityWidth; x++ {
+			i := y*stride + x*4
+			if wantData[i+3] != 0 {
+				painted++
+			}
+
+			tolerance := parityTolerance
+			if isEdge(wantData[i+3]) || isEdge(gotData[i+3]) {
+				tolerance = parityEdgeTolerance
+			}
+
+			for c := 0; c < 4; c++ {
+				diff := int(wantData[i+c]) - int(gotData[i+c])
+				if diff < -tolerance || diff > tolerance {
+					if mismatched < 10 {
+						t.Errorf("pixel (%d, %d) channel %d: cairo %d, snapshot %d",
+							x, y, c, wantData[i+c], gotData[i+c])
+					}
+					mismatched++
+					break
+				}
+			}
+		}
+	}
+
+	if painted == 0 {
+		t.Fatal("cairo renderer drew nothing")
+	}
+	if mismatched > 0 {
+		t.Errorf("%d of %d pixels differ", mismatched, parityWidth*parityHeight)
+	}
+}
+
+// isEdge returns whether a pixel of the given alpha is partly covered.
+func isEdge(alpha byte) bool {
+	return alpha != 0 && alpha != 0xFF
+}
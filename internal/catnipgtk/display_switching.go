@@ -0,0 +1,228 @@
+package catnipgtk
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/noriah/catnip/processor"
+)
+
+// SwitchingDisplay is a Display that shows one of several displays depending
+// on the draw style and renderer. All settings are forwarded to every display,
+// so switching between them does not require restarting catnip.
+type SwitchingDisplay struct {
+	*adw.Bin
+	cairo    *CairoDisplay
+	snapshot *SnapshotDisplay
+	scope    *OscilloscopeDisplay
+
+	style    DrawStyle
+	renderer Renderer
+	current  atomic.Pointer[switchingTarget]
+}
+
+type switchingTarget struct {
+	display Display
+	output  processor.Output
+	samples SampleOutput // nil if the display does not want samples
+}
+
+var (
+	_ SampleDisplay      = (*SwitchingDisplay)(nil)
+	_ RendererDisplay    = (*SwitchingDisplay)(nil)
+	_ InspectableDisplay = (*SwitchingDisplay)(nil)
+)
+
+// NewSwitchingDisplay creates a new SwitchingDisplay.
+func NewSwitchingDisplay(sampleRate float64, sampleSize int) *SwitchingDisplay {
+	d := &SwitchingDisplay{
+		cairo:    NewCairoDisplay(sampleRate, sampleSize),
+		snapshot: NewSnapshotDisplay(sampleRate, sampleSize),
+		scope:    NewOscilloscopeDisplay(),
+		style:    DrawBottomBars,
+		renderer: RendererCairo,
+	}
+
+	d.Bin = adw.NewBin()
+	d.Bin.AddCSSClass("catnip-switching-display")
+	d.update()
+
+	return d
+}
+
+func (d *SwitchingDisplay) displays() []Display {
+	return []Display{d.cairo, d.snapshot, d.scope}
+}
+
+// update switches to the display for the current style and renderer.
+func (d *SwitchingDisplay) update() {
+	switch {
+	case d.style == DrawOscilloscope:
+		d.switchTo(d.scope)
+	case d.renderer == RendererSnapshot:
+		d.switchTo(d.snapshot)
+	default:
+		d.switchTo(d.cairo)
+	}
+}
+
+func (d *SwitchingDisplay) switchTo(display Display) {
+	if current := d.current.Load(); current != nil && current.display == display {
+		return
+	}
+
+	target := &switchingTarget{
+		display: display,
+		output:  display.AsOutput(),
+	}
+	if sampleDisplay, ok := display.(SampleDisplay); ok {
+		target.samples = sampleDisplay.AsSampleOutput()
+	}
+
+	d.current.Store(target)
+	d.Bin.SetChild(display)
+}
+
+// SetSizes sets the sizes of the bars and spaces in the display.
+func (d *SwitchingDisplay) SetSizes(bar, space float64) {
+	for _, display := range d.displays() {
+		display.SetSizes(bar, space)
+	}
+}
+
+// SetDrawStyle sets the draw style and switches to the display that draws it.
+func (d *SwitchingDisplay) SetDrawStyle(style DrawStyle) {
+	for _, display := range d.displays() {
+		display.SetDrawStyle(style)
+	}
+
+	d.style = style
+	d.update()
+}
+
+// SetRenderer sets the renderer used to draw the spectrum and switches to its
+// display.
+func (d *SwitchingDisplay) SetRenderer(renderer Renderer) {
+	d.renderer = renderer
+	d.update()
+}
+
+// SetLineCap sets the line cap.
+func (d *SwitchingDisplay) SetLineCap(lineCap cairo.LineCap) {
+	for _, display := range d.displays() {
+		display.SetLineCap(lineCap)
+	}
+}
+
+// SetSamplingParams sets the sampling rate and size.
+func (d *SwitchingDisplay) SetSamplingParams(rate float64, size int) {
+	for _, display := range d.displays() {
+		display.SetSamplingParams(rate, size)
+	}
+}
+
+// SetWaterfallParams sets the parameters of the waterfall.
+func (d *SwitchingDisplay) SetWaterfallParams(history int, direction ScrollDirection, colorMap ColorMap) {
+	for _, display := range d.displays() {
+		display.SetWaterfallParams(history, direction, colorMap)
+	}
+}
+
+// SetPeakCaps sets the parameters of the peak caps.
+func (d *SwitchingDisplay) SetPeakCaps(hold time.Duration, gravity, thickness float64) {
+	for _, display := range d.displays() {
+		display.SetPeakCaps(hold, gravity, thickness)
+	}
+}
+
+// SetColors sets how the displays are colored.
+func (d *SwitchingDisplay) SetColors(mode ColorMode, left, right Gradient) {
+	for _, display := range d.displays() {
+		display.SetColors(mode, left, right)
+	}
+}
+
+// SetScaling sets how the displays scale the bins.
+func (d *SwitchingDisplay) SetScaling(scaling Scaling) {
+	for _, display := range d.displays() {
+		display.SetScaling(scaling)
+	}
+}
+
+// SetFrequencyMapping sets the frequency mapping of the displays.
+func (d *SwitchingDisplay) SetFrequencyMapping(mapping FrequencyMapping) {
+	for _, display := range d.displays() {
+		display.SetFrequencyMapping(mapping)
+	}
+}
+
+// SetAxes sets whether the displays draw their axes.
+func (d *SwitchingDisplay) SetAxes(show bool) {
+	for _, display := range d.displays() {
+		display.SetAxes(show)
+	}
+}
+
+// SetPauseWhenIdle sets whether the displays stop drawing while the input is
+// silent.
+func (d *SwitchingDisplay) SetPauseWhenIdle(pause bool) {
+	for _, display := range d.displays() {
+		display.SetPauseWhenIdle(pause)
+	}
+}
+
+// SetFrameRate sets the frame rate of the displays.
+func (d *SwitchingDisplay) SetFrameRate(fps int) {
+	for _, display := range d.displays() {
+		display.SetFrameRate(fps)
+	}
+}
+
+// SetInterpolation sets whether the displays blend between the frames of the
+// analyzer.
+func (d *SwitchingDisplay) SetInterpolation(interpolate bool) {
+	for _, display := range d.displays() {
+		display.SetInterpolation(interpolate)
+	}
+}
+
+// BinAt returns the bin at the given point of the current display, if it can
+// tell.
+func (d *SwitchingDisplay) BinAt(x, y float64) (BinInfo, bool) {
+	display, ok := d.current.Load().display.(InspectableDisplay)
+	if !ok {
+		return BinInfo{}, false
+	}
+	return display.BinAt(x, y)
+}
+
+// AsOutput returns an output that writes to the current display.
+func (d *SwitchingDisplay) AsOutput() DiscardableOutput {
+	return WrapDiscardableOutput((*switchingOutput)(d))
+}
+
+type switchingOutput SwitchingDisplay
+
+func (d *switchingOutput) Bins(nchannels int) int {
+	return d.current.Load().output.Bins(nchannels)
+}
+
+func (d *switchingOutput) Write(bins [][]float64, nchannels int) error {
+	return d.current.Load().output.Write(bins, nchannels)
+}
+
+// AsSampleOutput returns a SampleOutput that writes to the current display if
+// it wants samples.
+func (d *SwitchingDisplay) AsSampleOutput() SampleOutput {
+	return (*switchingSampleOutput)(d)
+}
+
+type switchingSampleOutput SwitchingDisplay
+
+func (d *switchingSampleOutput) WriteSamples(samples [][]float64) {
+	if output := d.current.Load().samples; output != nil {
+		output.WriteSamples(samples)
+	}
+}
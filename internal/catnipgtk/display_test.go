@@ -0,0 +1,133 @@
+package catnipgtk
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeFrameClock stands in for the frame clock of a widget. Its times are in
+// µs, like gdk.FrameClock.FrameTime.
+type fakeFrameClock struct {
+	now int64
+}
+
+func (c *fakeFrameClock) advance(d time.Duration) int64 {
+	c.now += d.Microseconds()
+	return c.now
+}
+
+func TestFrameTickerRate(t *testing.T) {
+	tests := []struct {
+		name    string
+		refresh int // frames per second of the display
+		jitter  time.Duration
+		fps     int
+		want    int // frames drawn in a second
+	}{
+		{"unlimited", 60, 0, 0, 60},
+		{"half", 60, 0, 30, 30},
+		{"jitter", 60, 400 * time.Microsecond, 60, 60},
+		{"above refresh", 60, 0, 144, 60},
+		// Every 2.4th frame on average.
+		{"uneven", 144, 0, 60, 60},
+		{"uneven jitter", 144, 400 * time.Microsecond, 60, 60},
+		{"uneven half", 144, 0, 30, 30},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var draws int
+			ticker := &frameTicker{
+				draw:    func() { draws++ },
+				changed: func() bool { return true },
+				idle:    func() bool { return false },
+			}
+			ticker.setFrameRate(test.fps)
+
+			clock := fakeFrameClock{now: 1_000_000}
+			period := time.Second / time.Duration(test.refresh)
+
+			for i := 0; i < test.refresh; i++ {
+				d := period
+				if i%2 == 0 {
+					d += test.jitter
+				} else {
+					d -= test.jitter
+				}
+				if !ticker.tick(clock.advance(d)) {
+					t.Fatal("ticker stopped while not idle")
+				}
+			}
+
+			if draws != test.want {
+				t.Errorf("drew %d frames, want %d", draws, test.want)
+			}
+		})
+	}
+}
+
+func TestFrameTickerSkipsMissedFrames(t *testing.T) {
+	var draws int
+	ticker := &frameTicker{
+		draw:    func() { draws++ },
+		changed: func() bool { return true },
+		idle:    func() bool { return false },
+	}
+	ticker.setFrameRate(30)
+
+	clock := fakeFrameClock{now: 1_000_000}
+	ticker.tick(clock.advance(time.Second / 60))
+
+	// After a long pause, the frames that were missed are not drawn all at
+	// once.
+	clock.advance(time.Second)
+	for i := 0; i < 4; i++ {
+		ticker.tick(clock.advance(time.Second / 60))
+	}
+
+	if draws != 3 {
+		t.Errorf("drew %d frames, want 3", draws)
+	}
+}
+
+func TestFrameTickerDrawsOnlyChanged(t *testing.T) {
+	changes := []bool{true, false, false, true, true, false}
+
+	var draws, i int
+	ticker := &frameTicker{
+		draw:    func() { draws++ },
+		changed: func() bool { i++; return changes[i-1] },
+		idle:    func() bool { return false },
+	}
+
+	var clock fakeFrameClock
+	for range changes {
+		ticker.tick(clock.advance(time.Second / 60))
+	}
+
+	if draws != 3 {
+		t.Errorf("drew %d frames, want 3", draws)
+	}
+}
+
+func TestFrameTickerStopsWhenIdle(t *testing.T) {
+	var idle bool
+	ticker := &frameTicker{
+		draw:    func() {},
+		changed: func() bool { return false },
+		idle:    func() bool { return idle },
+	}
+
+	var clock fakeFrameClock
+	if !ticker.tick(clock.advance(time.Second / 60)) {
+		t.Fatal("ticker stopped while not idle")
+	}
+
+	idle = true
+	if ticker.tick(clock.advance(time.Second / 60)) {
+		t.Fatal("ticker kept ticking while idle")
+	}
+	if !ticker.stopped.Load() {
+		t.Error("ticker is not marked as stopped")
+	}
+}
@@ -0,0 +1,131 @@
+package catnipgtk
+
+import "math"
+
+// FrequencyScale is how frequencies are spread across the bins.
+type FrequencyScale string
+
+const (
+	// FrequencyLinear gives every bin the same width in Hz.
+	FrequencyLinear FrequencyScale = "linear"
+	// FrequencyLog gives every bin the same width in octaves.
+	FrequencyLog FrequencyScale = "log"
+	// FrequencyMel gives every bin the same width on the mel scale, which is
+	// close to linear in the bass and logarithmic in the treble.
+	FrequencyMel FrequencyScale = "mel"
+	// FrequencyOctave3 groups the bins into 1/3 octave bands.
+	FrequencyOctave3 FrequencyScale = "octave3"
+	// FrequencyOctave6 groups the bins into 1/6 octave bands.
+	FrequencyOctave6 FrequencyScale = "octave6"
+	// FrequencyOctave12 groups the bins into 1/12 octave bands, which are
+	// semitones.
+	FrequencyOctave12 FrequencyScale = "octave12"
+)
+
+// bandsPerOctave returns the number of bands per octave, or 0 if the scale
+// does not use bands.
+func (s FrequencyScale) bandsPerOctave() int {
+	switch s {
+	case FrequencyOctave3:
+		return 3
+	case FrequencyOctave6:
+		return 6
+	case FrequencyOctave12:
+		return 12
+	default:
+		return 0
+	}
+}
+
+// FrequencyMapping maps bins to the frequencies that they cover.
+type FrequencyMapping struct {
+	Scale FrequencyScale
+	Min   float64 // Hz
+	Max   float64 // Hz
+}
+
+// Clamp returns the mapping with its range clamped to what can be sampled at
+// the given sample rate.
+func (m FrequencyMapping) Clamp(sampleRate float64) FrequencyMapping {
+	if m.Max <= 0 || m.Max > sampleRate/2 {
+		m.Max = sampleRate / 2
+	}
+	m.Min = math.Max(m.Min, 1)
+	if m.Min >= m.Max {
+		m.Min = m.Max / 2
+	}
+	return m
+}
+
+// Range returns the lowest and highest frequencies covered by the ith of n
+// bins. With octave bands, neighboring bins cover the same band if there are
+// more bins than bands.
+func (m FrequencyMapping) Range(i, n int) (lo, hi float64) {
+	if bpo := m.Scale.bandsPerOctave(); bpo > 0 {
+		return m.bandRange(bpo, i, n)
+	}
+
+	return m.at(float64(i) / float64(n)), m.at(float64(i+1) / float64(n))
+}
+
+// Center returns the center frequency of the ith of n bins.
+func (m FrequencyMapping) Center(i, n int) float64 {
+	lo, hi := m.Range(i, n)
+	if m.Scale == FrequencyLinear {
+		return (lo + hi) / 2
+	}
+	return math.Sqrt(lo * hi)
+}
+
+// Position returns where the frequency lies on the scale, from 0 at Min to 1
+// at Max. It is the inverse of the mapping for all scales but the octave
+// bands, which are treated as logarithmic.
+func (m FrequencyMapping) Position(freq float64) float64 {
+	switch m.Scale {
+	case FrequencyLinear:
+		return (freq - m.Min) / (m.Max - m.Min)
+	case FrequencyMel:
+		return (hzToMel(freq) - hzToMel(m.Min)) / (hzToMel(m.Max) - hzToMel(m.Min))
+	default:
+		return math.Log(freq/m.Min) / math.Log(m.Max/m.Min)
+	}
+}
+
+// at returns the frequency at t in [0, 1] along a continuous scale.
+func (m FrequencyMapping) at(t float64) float64 {
+	switch m.Scale {
+	case FrequencyLinear:
+		return m.Min + (m.Max-m.Min)*t
+	case FrequencyMel:
+		lo, hi := hzToMel(m.Min), hzToMel(m.Max)
+		return melToHz(lo + (hi-lo)*t)
+	default:
+		return m.Min * math.Pow(m.Max/m.Min, t)
+	}
+}
+
+// bandRange returns the range of the ith of n bins when the bins are grouped
+// into fractional octave bands centered around 1 kHz.
+func (m FrequencyMapping) bandRange(bpo, i, n int) (lo, hi float64) {
+	// Band k is centered at 1000 * 2^(k/bpo) and spans half a band to
+	// either side.
+	first := int(math.Ceil(float64(bpo) * math.Log2(m.Min/1000)))
+	last := int(math.Floor(float64(bpo) * math.Log2(m.Max/1000)))
+	nbands := max(1, last-first+1)
+
+	from := first + i*nbands/n
+	to := max(from, first+(i+1)*nbands/n-1)
+
+	halfBand := math.Pow(2, 1/(2*float64(bpo)))
+	lo = 1000 * math.Pow(2, float64(from)/float64(bpo)) / halfBand
+	hi = 1000 * math.Pow(2, float64(to)/float64(bpo)) * halfBand
+	return lo, hi
+}
+
+func hzToMel(hz float64) float64 {
+	return 2595 * math.Log10(1+hz/700)
+}
+
+func melToHz(mel float64) float64 {
+	return 700 * (math.Pow(10, mel/2595) - 1)
+}
@@ -0,0 +1,185 @@
+package catnipgtk
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+)
+
+// inspectorInterval is how often the inspector's tooltip is refreshed while the
+// pointer is over the display, in milliseconds.
+const inspectorInterval = 100
+
+// InspectableDisplay is a Display that can tell what bin is under a point.
+type InspectableDisplay interface {
+	Display
+	// BinAt returns the bin drawn at the given point in widget coordinates.
+	// It returns false if there is no bin there.
+	BinAt(x, y float64) (BinInfo, bool)
+}
+
+// BinInfo describes a bin of the spectrum as it was last drawn.
+type BinInfo struct {
+	Channel   int
+	Bin       int
+	BinCount  int
+	Frequency float64 // center frequency in Hz
+	Level     float64 // dBFS
+}
+
+// Note returns the musical note nearest to the bin's frequency along with how
+// far off it is in cents, e.g. "A4 +3¢".
+func (b BinInfo) Note() string {
+	return noteName(b.Frequency)
+}
+
+// String formats the bin for display, e.g. "440 Hz · A4 +0¢ · -12.0 dB".
+func (b BinInfo) String() string {
+	freq := fmt.Sprintf("%.0f Hz", b.Frequency)
+	if b.Frequency >= 1000 {
+		freq = fmt.Sprintf("%.2f kHz", b.Frequency/1000)
+	}
+	return fmt.Sprintf("%s · %s · %.1f dB", freq, b.Note(), b.Level)
+}
+
+var noteNames = [12]string{"C", "C♯", "D", "D♯", "E", "F", "F♯", "G", "G♯", "A", "A♯", "B"}
+
+func noteName(freq float64) string {
+	if freq <= 0 {
+		return "?"
+	}
+
+	// MIDI note 69 is A4 at 440 Hz.
+	midi := 69 + 12*math.Log2(freq/440)
+	note := math.Round(midi)
+
+	cents := math.Round((midi - note) * 100)
+	if cents == 0 {
+		cents = 0 // avoid printing -0
+	}
+
+	name := noteNames[(int(note)%12+12)%12]
+	octave := int(math.Floor(note/12)) - 1
+
+	return fmt.Sprintf("%s%d %+.0f¢", name, octave, cents)
+}
+
+// BinAt returns the bin drawn at the given point.
+func (d *spectrum) BinAt(x, y float64) (BinInfo, bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	nbars := d.bins(d.nchannels)
+	if nbars <= 0 || d.nchannels <= 0 {
+		return BinInfo{}, false
+	}
+
+	ch, bin, ok := d.binAt(x, y, nbars)
+	if !ok || ch >= len(d.binsBuffer) || bin >= len(d.binsBuffer[ch]) {
+		return BinInfo{}, false
+	}
+
+	value := d.binsBuffer[ch][bin]
+
+	var level float64
+	if d.scaling.Auto {
+		level = logMagnitudeToDecibels(value, d.sampleSize)
+	} else {
+		level = d.scaling.Decibels.denormalize(value)
+	}
+
+	return BinInfo{
+		Channel:   ch,
+		Bin:       bin,
+		BinCount:  nbars,
+		Frequency: d.mapping.Center(bin, nbars),
+		Level:     level,
+	}, true
+}
+
+// binAt returns the channel and bin at the given point. The lock must be held.
+func (d *spectrum) binAt(x, y float64, nbars int) (ch, bin int, ok bool) {
+	if d.drawStyle != DrawCircle {
+		for _, span := range d.frequencySpans(float64(d.width)) {
+			if bin, ok := span.bin(x); ok {
+				return span.ch, bin, true
+			}
+		}
+		return 0, 0, false
+	}
+
+	// See drawCircle. The first channel goes clockwise from the top of the
+	// ring and the second channel goes counterclockwise.
+	wf := float64(d.width)
+	hf := float64(d.height)
+
+	dx := x - wf/2
+	dy := y - hf/2
+	if math.Hypot(dx, dy) < d.circleRadius(wf, hf) {
+		return 0, 0, false
+	}
+
+	// Angle from the top of the ring, clockwise, in (-π, π].
+	angle := math.Atan2(dx, -dy)
+	if angle < 0 {
+		if d.nchannels < 2 {
+			return 0, 0, false
+		}
+		ch = 1
+		angle = -angle
+	}
+
+	step := 2 * math.Pi / float64(d.nchannels*nbars)
+	bin = int(angle / step)
+	if bin >= nbars {
+		return 0, 0, false
+	}
+
+	return ch, bin, true
+}
+
+// BindInspector makes the display show the frequency, nearest note and level
+// of the bin under the pointer in its tooltip.
+func BindInspector(display InspectableDisplay) {
+	widget := gtk.BaseWidget(display)
+
+	var x, y float64
+	var refresh glib.SourceHandle
+
+	update := func() {
+		if info, ok := display.BinAt(x, y); ok {
+			widget.SetTooltipText(info.String())
+		} else {
+			widget.SetTooltipText("")
+		}
+	}
+
+	motion := gtk.NewEventControllerMotion()
+	motion.ConnectEnter(func(px, py float64) {
+		x, y = px, py
+		update()
+
+		// The level keeps changing even if the pointer stays still.
+		if refresh == 0 {
+			refresh = glib.TimeoutAdd(inspectorInterval, func() bool {
+				update()
+				return true
+			})
+		}
+	})
+	motion.ConnectMotion(func(px, py float64) {
+		x, y = px, py
+		update()
+	})
+	motion.ConnectLeave(func() {
+		if refresh != 0 {
+			glib.SourceRemove(refresh)
+			refresh = 0
+		}
+		widget.SetTooltipText("")
+	})
+
+	widget.AddController(motion)
+}
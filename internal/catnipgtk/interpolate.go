@@ -0,0 +1,70 @@
+package catnipgtk
+
+import (
+	"time"
+
+	"github.com/noriah/catnip/input"
+)
+
+// maxFramePeriod is the longest time between two analyzer frames that is
+// still blended over. Longer gaps, such as after a pause, jump to the new
+// frame.
+const maxFramePeriod = 250 * time.Millisecond
+
+// frameInterpolator blends between the last two frames of the analyzer, so
+// that the bins move smoothly on displays that are drawn more often than the
+// analyzer runs. The drawn bins lag behind by up to one analyzer frame.
+type frameInterpolator struct {
+	enabled  bool
+	blending bool // true until the last frame is reached
+
+	from   [][]float64
+	to     [][]float64
+	at     time.Time     // when to was pushed
+	period time.Duration // estimated time between frames
+}
+
+// push starts blending from the bins that are drawn now to the new ones.
+func (f *frameInterpolator) push(drawn, bins [][]float64, now time.Time) {
+	if !f.at.IsZero() {
+		switch dt := now.Sub(f.at); {
+		case dt >= maxFramePeriod:
+			f.period = 0
+		case f.period == 0:
+			f.period = dt
+		default:
+			// Smooth out the jitter of the analyzer.
+			f.period = (3*f.period + dt) / 4
+		}
+	}
+	f.at = now
+
+	if len(f.to) != len(bins) || len(f.to[0]) != len(bins[0]) {
+		f.from = input.MakeBuffers(len(bins), len(bins[0]))
+		f.to = input.MakeBuffers(len(bins), len(bins[0]))
+	}
+	input.CopyBuffers(f.from, drawn)
+	input.CopyBuffers(f.to, bins)
+
+	f.blending = true
+}
+
+// blend writes the bins at the given time into dst, which must be the same
+// size as the pushed bins.
+func (f *frameInterpolator) blend(dst [][]float64, now time.Time) {
+	t := 1.0
+	if f.period > 0 {
+		t = float64(now.Sub(f.at)) / float64(f.period)
+	}
+	if t >= 1 {
+		t = 1
+		f.blending = false
+	}
+	t = max(t, 0)
+
+	for ch, bins := range dst {
+		for i := range bins {
+			bins[i] = f.from[ch][i] + (f.to[ch][i]-f.from[ch][i])*t
+		}
+	}
+}
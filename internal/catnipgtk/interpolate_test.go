@@ -0,0 +1,142 @@
+package catnipgtk
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// fakeClock is a clock for the spectrum that only moves when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// constantBins returns stereo bins that are all v.
+func constantBins(v float64) [][]float64 {
+	return [][]float64{{v, v}, {v, v}}
+}
+
+func TestFrameInterpolatorBlends(t *testing.T) {
+	clock := fakeClock{now: time.Unix(0, 0)}
+	dst := constantBins(0)
+
+	var f frameInterpolator
+	f.enabled = true
+
+	// Let the interpolator learn that frames come every 20ms.
+	for i := 0; i < 10; i++ {
+		f.push(dst, constantBins(float64(i)), clock.Now())
+		clock.advance(20 * time.Millisecond)
+		f.blend(dst, clock.Now())
+	}
+	if f.period != 20*time.Millisecond {
+		t.Fatalf("period = %v, want 20ms", f.period)
+	}
+
+	f.push(dst, constantBins(19), clock.Now())
+
+	for _, step := range []struct {
+		after time.Duration
+		want  float64
+	}{
+		{0, 9},
+		{5 * time.Millisecond, 11.5},
+		{10 * time.Millisecond, 14},
+		{20 * time.Millisecond, 19},
+		{30 * time.Millisecond, 19},
+	} {
+		f.blend(dst, clock.Now().Add(step.after))
+		if got := dst[0][0]; math.Abs(got-step.want) > 1e-9 {
+			t.Errorf("after %v: bin = %v, want %v", step.after, got, step.want)
+		}
+	}
+
+	if f.blending {
+		t.Error("still blending after reaching the last frame")
+	}
+}
+
+func TestFrameInterpolatorJumpsAfterGap(t *testing.T) {
+	clock := fakeClock{now: time.Unix(0, 0)}
+	dst := constantBins(0)
+
+	var f frameInterpolator
+	f.push(dst, constantBins(1), clock.Now())
+	clock.advance(20 * time.Millisecond)
+	f.push(dst, constantBins(2), clock.Now())
+
+	clock.advance(maxFramePeriod)
+	f.push(dst, constantBins(3), clock.Now())
+	f.blend(dst, clock.Now())
+
+	if dst[0][0] != 3 {
+		t.Errorf("bin = %v after a pause, want 3", dst[0][0])
+	}
+}
+
+func newTestSpectrum(clock *fakeClock) *spectrum {
+	var s spectrum
+	s.init(44100, 1024)
+	s.now = clock.Now
+	// Two bins of 5px each, which is as many as constantBins has.
+	s.width = 10
+	s.height = 10
+	return &s
+}
+
+func TestSpectrumFramesOnlyNewData(t *testing.T) {
+	clock := fakeClock{now: time.Unix(0, 0)}
+	s := newTestSpectrum(&clock)
+	output := s.AsOutput()
+
+	// The settings changed since the start.
+	if !s.frame() {
+		t.Error("first frame has nothing to draw")
+	}
+	if s.frame() {
+		t.Error("frame without new data has something to draw")
+	}
+
+	output.Write(constantBins(0.5), 2)
+	if !s.frame() {
+		t.Error("frame after new data has nothing to draw")
+	}
+	if s.frame() {
+		t.Error("frame after drawing the new data has something to draw")
+	}
+}
+
+func TestSpectrumFramesWhileBlending(t *testing.T) {
+	clock := fakeClock{now: time.Unix(0, 0)}
+	s := newTestSpectrum(&clock)
+	s.SetInterpolation(true)
+	output := s.AsOutput()
+
+	for i := 0; i < 5; i++ {
+		output.Write(constantBins(float64(i%2)), 2)
+		clock.advance(20 * time.Millisecond)
+		s.frame()
+	}
+
+	output.Write(constantBins(1), 2)
+	var frames int
+	for s.frame() {
+		frames++
+		clock.advance(5 * time.Millisecond)
+		if frames > 100 {
+			t.Fatal("blending never finished")
+		}
+	}
+
+	// A frame every 5ms over a 20ms period, and the last one at the end.
+	if frames != 5 {
+		t.Errorf("%d frames to draw while blending, want 5", frames)
+	}
+	if s.binsBuffer[0][0] != 1 {
+		t.Errorf("bin = %v after blending, want 1", s.binsBuffer[0][0])
+	}
+}
@@ -0,0 +1,107 @@
+package catnipgtk
+
+import "time"
+
+// peakCaps keeps track of the recent peak of each bin. Each peak is held for a
+// while before it falls down with gravity, like the caps on hardware
+// analyzers.
+type peakCaps struct {
+	channels  [][]peakCap
+	hold      time.Duration
+	gravity   float64 // display heights per second squared
+	thickness float64 // 0 disables the caps
+	last      time.Time
+}
+
+type peakCap struct {
+	value    float64 // normalized to [0, 1]
+	velocity float64
+	heldAt   time.Time
+}
+
+func (p *peakCaps) enabled() bool {
+	return p.thickness > 0
+}
+
+// resize resizes the peaks to the given number of channels and bins. The
+// existing peaks are stretched over the new bins, so changing the bin count
+// does not reset them.
+func (p *peakCaps) resize(nchannels, nbins int) {
+	if len(p.channels) != nchannels {
+		old := p.channels
+		p.channels = make([][]peakCap, nchannels)
+		copy(p.channels, old)
+	}
+
+	for ch, peaks := range p.channels {
+		if len(peaks) == nbins {
+			continue
+		}
+
+		resized := make([]peakCap, nbins)
+		if len(peaks) > 0 {
+			for i := range resized {
+				resized[i] = peaks[i*len(peaks)/nbins]
+			}
+		}
+		p.channels[ch] = resized
+	}
+}
+
+// update updates the peaks with the given bins, which are normalized using the
+// given scale.
+func (p *peakCaps) update(bins [][]float64, nbins int, scale float64, now time.Time) {
+	var dt float64
+	if !p.last.IsZero() {
+		dt = now.Sub(p.last).Seconds()
+	}
+	p.last = now
+
+	p.resize(len(bins), nbins)
+
+	for ch, peaks := range p.channels {
+		for i := range peaks {
+			peak := &peaks[i]
+			value := min(bins[ch][i]/scale, 1)
+
+			if now.Sub(peak.heldAt) > p.hold && peak.value > 0 {
+				peak.velocity += p.gravity * dt
+				peak.value -= peak.velocity * dt
+				if peak.value <= 0 {
+					// Rest on the baseline instead of falling through it.
+					peak.value = 0
+					peak.velocity = 0
+				}
+			}
+
+			if value >= peak.value {
+				peak.value = value
+				peak.velocity = 0
+				peak.heldAt = now
+			}
+		}
+	}
+}
+
+// at returns the peak of the given bin. It returns false if there is no peak.
+func (p *peakCaps) at(ch, bin int) (float64, bool) {
+	if !p.enabled() || ch >= len(p.channels) || bin >= len(p.channels[ch]) {
+		return 0, false
+	}
+	return p.channels[ch][bin].value, true
+}
+
+// visible returns whether any of the peaks is still above the bottom.
+func (p *peakCaps) visible() bool {
+	if !p.enabled() {
+		return false
+	}
+	for _, peaks := range p.channels {
+		for _, peak := range peaks {
+			if peak.value > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package preferences
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// colorStopsEditor edits the color stops of a gradient inside an ExpanderRow.
+type colorStopsEditor struct {
+	row     *adw.ExpanderRow
+	add     *gtk.Button
+	rows    []*adw.ActionRow
+	stops   []catnipgtk.ColorStop
+	changed func(catnipgtk.Gradient)
+}
+
+func newColorStopsEditor(row *adw.ExpanderRow, changed func(catnipgtk.Gradient)) *colorStopsEditor {
+	e := &colorStopsEditor{
+		row:     row,
+		changed: changed,
+	}
+
+	e.add = gtk.NewButtonFromIconName("list-add-symbolic")
+	e.add.SetVAlign(gtk.AlignCenter)
+	e.add.SetTooltipText("Add Color Stop")
+	e.add.AddCSSClass("flat")
+	e.add.ConnectClicked(func() {
+		stop := catnipgtk.ColorStop{Offset: 1, Color: catnipgtk.Color{R: 1, G: 1, B: 1, A: 1}}
+		if len(e.stops) > 0 {
+			stop.Color = e.stops[len(e.stops)-1].Color
+		}
+
+		e.stops = append(e.stops, stop)
+		e.rebuild()
+		e.emit()
+	})
+	e.row.AddAction(e.add)
+
+	return e
+}
+
+// SetGradient sets the gradient being edited without emitting a change.
+func (e *colorStopsEditor) SetGradient(gradient catnipgtk.Gradient) {
+	e.stops = gradient.Stops()
+	e.rebuild()
+}
+
+func (e *colorStopsEditor) emit() {
+	stops := append([]catnipgtk.ColorStop(nil), e.stops...)
+	sort.SliceStable(stops, func(i, j int) bool {
+		return stops[i].Offset < stops[j].Offset
+	})
+
+	e.changed(catnipgtk.NewGradient(stops...))
+}
+
+func (e *colorStopsEditor) rebuild() {
+	for _, row := range e.rows {
+		e.row.Remove(row)
+	}
+	e.rows = e.rows[:0]
+
+	for i := range e.stops {
+		row := e.newStopRow(i)
+		e.row.AddRow(row)
+		e.rows = append(e.rows, row)
+	}
+
+	e.add.SetSensitive(len(e.stops) < catnipgtk.MaxColorStops)
+}
+
+func (e *colorStopsEditor) newStopRow(i int) *adw.ActionRow {
+	stop := e.stops[i]
+
+	offset := gtk.NewSpinButtonWithRange(0, 1, 0.05)
+	offset.SetDigits(2)
+	offset.SetVAlign(gtk.AlignCenter)
+	offset.SetValue(stop.Offset)
+	offset.SetTooltipText("Offset")
+	offset.ConnectValueChanged(func() {
+		e.stops[i].Offset = offset.Value()
+		e.emit()
+	})
+
+	rgba := gdk.NewRGBA(float32(stop.Color.R), float32(stop.Color.G), float32(stop.Color.B), float32(stop.Color.A))
+
+	color := gtk.NewColorButtonWithRGBA(&rgba)
+	color.SetVAlign(gtk.AlignCenter)
+	color.SetUseAlpha(true)
+	color.ConnectColorSet(func() {
+		rgba := color.RGBA()
+		e.stops[i].Color = catnipgtk.Color{
+			R: float64(rgba.Red()),
+			G: float64(rgba.Green()),
+			B: float64(rgba.Blue()),
+			A: float64(rgba.Alpha()),
+		}
+		e.emit()
+	})
+
+	remove := gtk.NewButtonFromIconName("list-remove-symbolic")
+	remove.SetVAlign(gtk.AlignCenter)
+	remove.SetTooltipText("Remove Color Stop")
+	remove.AddCSSClass("flat")
+	remove.SetSensitive(len(e.stops) > 1)
+	remove.ConnectClicked(func() {
+		e.stops = append(e.stops[:i], e.stops[i+1:]...)
+		e.rebuild()
+		e.emit()
+	})
+
+	row := adw.NewActionRow()
+	row.SetTitle(fmt.Sprintf("Stop %d", i+1))
+	row.AddSuffix(offset)
+	row.AddSuffix(color)
+	row.AddSuffix(remove)
+
+	return row
+}
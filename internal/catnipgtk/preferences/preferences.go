@@ -15,6 +15,7 @@ import (
 	"github.com/noriah/catnip/input"
 	"libdb.so/catnip-gtk4/internal/catnipctl"
 	"libdb.so/catnip-gtk4/internal/catnipgtk"
+	"libdb.so/catnip-gtk4/internal/fileinput"
 )
 
 //go:embed preferences.blueprint.ui
@@ -29,21 +30,62 @@ type Preferences struct {
 		Preference         *adw.PreferencesWindow `name:"preference"`
 		Backend            *adw.ComboRow          `name:"backend"`
 		Device             *adw.ComboRow          `name:"device"`
+		FileFolderRow      *adw.ActionRow         `name:"fileFolderRow"`
+		FileFolder         *gtk.Button            `name:"fileFolder"`
+		FileLoopRow        *adw.ActionRow         `name:"fileLoopRow"`
+		FileLoop           *gtk.Switch            `name:"fileLoop"`
 		Monaural           *gtk.Switch            `name:"monaural"`
 		SamplingGroup      *adw.PreferencesGroup  `name:"samplingGroup"`
 		SampleRate         *gtk.SpinButton        `name:"sampleRate"`
 		SampleSize         *gtk.SpinButton        `name:"sampleSize"`
 		WindowFunc         *adw.ComboRow          `name:"windowFunc"`
+		FrequencyScale     *adw.ComboRow          `name:"frequencyScale"`
+		MinFrequency       *gtk.SpinButton        `name:"minFrequency"`
+		MaxFrequency       *gtk.SpinButton        `name:"maxFrequency"`
 		SmoothFactor       *gtk.SpinButton        `name:"smoothFactor"`
+		MagnitudeScale     *adw.ComboRow          `name:"magnitudeScale"`
+		DecibelFloor       *gtk.SpinButton        `name:"decibelFloor"`
+		DecibelCeiling     *gtk.SpinButton        `name:"decibelCeiling"`
+		Weighting          *adw.ComboRow          `name:"weighting"`
+		ScalingWindow      *gtk.SpinButton        `name:"scalingWindow"`
+		PeakThreshold      *gtk.SpinButton        `name:"peakThreshold"`
+		ZeroThreshold      *gtk.SpinButton        `name:"zeroThreshold"`
 		DrawStyle          *adw.ComboRow          `name:"drawStyle"`
+		ShowAxes           *gtk.Switch            `name:"showAxes"`
 		LineCap            *adw.ComboRow          `name:"lineCap"`
 		LineWidth          *gtk.SpinButton        `name:"lineWidth"`
 		GapWidth           *gtk.SpinButton        `name:"gapWidth"`
+		WaterfallHistory   *gtk.SpinButton        `name:"waterfallHistory"`
+		WaterfallDirection *adw.ComboRow          `name:"waterfallDirection"`
+		ColorMap           *adw.ComboRow          `name:"colorMap"`
+		PeakCapThickness   *gtk.SpinButton        `name:"peakCapThickness"`
+		PeakHoldTime       *gtk.SpinButton        `name:"peakHoldTime"`
+		PeakFallRate       *gtk.SpinButton        `name:"peakFallRate"`
+		ColorMode          *adw.ComboRow          `name:"colorMode"`
+		SplitChannelColors *gtk.Switch            `name:"splitChannelColors"`
+		ColorStops         *adw.ExpanderRow       `name:"colorStops"`
+		RightColorStops    *adw.ExpanderRow       `name:"rightColorStops"`
 		OpenCustomCSS      *gtk.Button            `name:"openCustomCSS"`
 		ShowWindowControls *gtk.Switch            `name:"showWindowControls"`
+		Renderer           *adw.ComboRow          `name:"renderer"`
+		PauseWhenIdle      *gtk.Switch            `name:"pauseWhenIdle"`
+		FrameRate          *gtk.SpinButton        `name:"frameRate"`
+		Interpolate        *gtk.Switch            `name:"interpolate"`
+		Profile            *adw.ComboRow          `name:"profile"`
+		ProfileName        *gtk.Entry             `name:"profileName"`
+		NewProfile         *gtk.Button            `name:"newProfile"`
+		DuplicateProfile   *gtk.Button            `name:"duplicateProfile"`
+		RenameProfile      *gtk.Button            `name:"renameProfile"`
+		DeleteProfile      *gtk.Button            `name:"deleteProfile"`
 	}
-	controlling *catnipctl.Instance
-	ctx         context.Context
+	colorStops      *colorStopsEditor
+	rightColorStops *colorStopsEditor
+	devices         *catnipctl.DeviceMonitor
+	deviceNames     []string // "" for the default device
+	loadingDevices  bool
+	profiles        []string // "" for the default config
+	controlling     *catnipctl.Instance
+	ctx             context.Context
 }
 
 // NewPreferences creates a new preferences window.
@@ -58,13 +100,25 @@ func NewPreferences(controlling *catnipctl.Instance) *Preferences {
 
 	p.PreferencesWindow = p.built.Preference
 
+	// Keep the devices current while the preferences are shown.
+	p.devices = catnipctl.NewDeviceMonitor(controlling.Config().Backend, catnipctl.DeviceMonitorInterval)
+	p.devices.ConnectChanged(func(devices []string) {
+		p.setDevices(devices, p.controlling.Config().Device, true)
+	})
+	p.ConnectMap(p.devices.Start)
+	p.ConnectUnmap(p.devices.Stop)
+
 	p.built.Backend.SetModel(gtk.NewStringList(input.GetAllBackendNames()))
 	p.built.WindowFunc.SetModel(windowFuncsModel)
+	p.built.FrequencyScale.SetModel(frequencyScalesModel)
+	p.built.MagnitudeScale.SetModel(magnitudeScalesModel)
+	p.built.Weighting.SetModel(weightingsModel)
 	p.built.DrawStyle.SetModel(drawStylesModel)
 	p.built.LineCap.SetModel(lineCapsModel)
-
-	var deviceNames []string
-	var deviceNamesModel *gtk.StringList
+	p.built.WaterfallDirection.SetModel(scrollDirectionsModel)
+	p.built.ColorMap.SetModel(colorMapsModel)
+	p.built.ColorMode.SetModel(colorModesModel)
+	p.built.Renderer.SetModel(renderersModel)
 
 	p.built.Backend.NotifyProperty("selected", func() {
 		defer p.save(p.controlling.Config())
@@ -79,37 +133,49 @@ func NewPreferences(controlling *catnipctl.Instance) *Preferences {
 			device = config.Device
 		})
 
-		devices, err := backend.Devices()
-		if err != nil {
-			log.Println("Failed to get devices:", err)
-			return
-		}
+		// Try to restore the previous device when switching backends.
+		p.loadDevices(backend, device, false)
+	})
 
-		deviceNames = append(
-			[]string{"(default)"},
-			mapSlice(devices, input.Device.String)...,
-		)
-		deviceNamesModel = gtk.NewStringList(deviceNames)
-		p.built.Device.SetModel(deviceNamesModel)
+	p.built.FileFolder.ConnectClicked(func() {
+		chooser := gtk.NewFileChooserNative(
+			"Choose Folder", &p.PreferencesWindow.Window.Window,
+			gtk.FileChooserActionSelectFolder, "Choose", "Cancel")
+		chooser.SetModal(true)
+		chooser.ConnectResponse(func(response int) {
+			if response != int(gtk.ResponseAccept) {
+				return
+			}
+
+			folder := chooser.File().Path()
+			p.setFileFolder(folder)
+
+			resume := p.controlling.PauseUpdates()
+			defer resume()
+
+			var device string
+			p.update(func(config *catnipgtk.Config) {
+				config.FileFolder = folder
+				device = config.Device
+			})
+
+			p.loadDevices(input.Backends[p.built.Backend.Selected()], device, false)
+		})
+		chooser.Show()
+	})
 
-		// Try to restore the previous device when switching backends,
-		// defaulting to the first device if not found. The emitted signal will
-		// update the config.
-		log.Println("Restoring device:", device)
-		p.built.Device.SetSelected(uint(findOr(deviceNames, device, 0)))
+	p.built.FileLoop.NotifyProperty("active", func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.FileLoop = p.built.FileLoop.Active()
+		})
 	})
 
 	p.built.Device.NotifyProperty("selected", func() {
-		if deviceNamesModel == nil {
-			log.Println("unexpected nil devicesModel")
+		if p.loadingDevices {
 			return
 		}
 
-		device := deviceNamesModel.String(p.built.Device.Selected())
-		if device == "(default)" {
-			device = ""
-		}
-
+		device := p.deviceNames[p.built.Device.Selected()]
 		p.update(func(config *catnipgtk.Config) {
 			config.Device = device
 		})
@@ -153,18 +219,87 @@ func NewPreferences(controlling *catnipctl.Instance) *Preferences {
 		})
 	})
 
+	p.built.FrequencyScale.NotifyProperty("selected", func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.FrequencyScale = frequencyScales[p.built.FrequencyScale.Selected()]
+		})
+	})
+
+	p.built.MinFrequency.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.MinFrequency = p.built.MinFrequency.Value()
+		})
+	})
+
+	p.built.MaxFrequency.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.MaxFrequency = p.built.MaxFrequency.Value()
+		})
+	})
+
 	p.built.SmoothFactor.ConnectValueChanged(func() {
 		p.update(func(config *catnipgtk.Config) {
 			config.SmoothingFactor = p.built.SmoothFactor.Value()
 		})
 	})
 
+	p.built.MagnitudeScale.NotifyProperty("selected", func() {
+		scale := magnitudeScales[p.built.MagnitudeScale.Selected()]
+		p.setDecibelRowsSensitive(scale == catnipgtk.MagnitudeDecibel)
+
+		p.update(func(config *catnipgtk.Config) {
+			config.MagnitudeScale = scale
+		})
+	})
+
+	p.built.DecibelFloor.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.DecibelFloor = p.built.DecibelFloor.Value()
+		})
+	})
+
+	p.built.DecibelCeiling.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.DecibelCeiling = p.built.DecibelCeiling.Value()
+		})
+	})
+
+	p.built.Weighting.NotifyProperty("selected", func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.Weighting = weightings[p.built.Weighting.Selected()]
+		})
+	})
+
+	p.built.ScalingWindow.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.ScalingWindow = p.built.ScalingWindow.Value()
+		})
+	})
+
+	p.built.PeakThreshold.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.PeakThreshold = p.built.PeakThreshold.Value()
+		})
+	})
+
+	p.built.ZeroThreshold.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.ZeroThreshold = int(p.built.ZeroThreshold.Value())
+		})
+	})
+
 	p.built.DrawStyle.NotifyProperty("selected", func() {
 		p.update(func(config *catnipgtk.Config) {
 			config.DrawStyle = drawStyles[p.built.DrawStyle.Selected()]
 		})
 	})
 
+	p.built.ShowAxes.NotifyProperty("active", func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.ShowAxes = p.built.ShowAxes.Active()
+		})
+	})
+
 	p.built.LineCap.NotifyProperty("selected", func() {
 		p.update(func(config *catnipgtk.Config) {
 			config.LineCap = lineCaps[p.built.LineCap.Selected()]
@@ -183,6 +318,69 @@ func NewPreferences(controlling *catnipctl.Instance) *Preferences {
 		})
 	})
 
+	p.built.WaterfallHistory.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.WaterfallHistory = int(p.built.WaterfallHistory.Value())
+		})
+	})
+
+	p.built.WaterfallDirection.NotifyProperty("selected", func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.WaterfallDirection = scrollDirections[p.built.WaterfallDirection.Selected()]
+		})
+	})
+
+	p.built.ColorMap.NotifyProperty("selected", func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.ColorMap = colorMaps[p.built.ColorMap.Selected()]
+		})
+	})
+
+	p.built.PeakCapThickness.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.PeakCapThickness = p.built.PeakCapThickness.Value()
+		})
+	})
+
+	p.built.PeakHoldTime.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.PeakHoldTime = p.built.PeakHoldTime.Value()
+		})
+	})
+
+	p.built.PeakFallRate.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.PeakFallRate = p.built.PeakFallRate.Value()
+		})
+	})
+
+	p.built.ColorMode.NotifyProperty("selected", func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.ColorMode = colorModes[p.built.ColorMode.Selected()]
+		})
+	})
+
+	p.built.SplitChannelColors.NotifyProperty("active", func() {
+		split := p.built.SplitChannelColors.Active()
+		p.built.RightColorStops.SetSensitive(split)
+
+		p.update(func(config *catnipgtk.Config) {
+			config.SplitChannelColors = split
+		})
+	})
+
+	p.colorStops = newColorStopsEditor(p.built.ColorStops, func(gradient catnipgtk.Gradient) {
+		p.update(func(config *catnipgtk.Config) {
+			config.Gradient = gradient
+		})
+	})
+
+	p.rightColorStops = newColorStopsEditor(p.built.RightColorStops, func(gradient catnipgtk.Gradient) {
+		p.update(func(config *catnipgtk.Config) {
+			config.RightGradient = gradient
+		})
+	})
+
 	p.built.OpenCustomCSS.ConnectClicked(func() {
 		app.OpenURI(p.ctx, "file://"+filepath.ToSlash(catnipgtk.ConfigDir)+"/user.css")
 	})
@@ -193,26 +391,163 @@ func NewPreferences(controlling *catnipctl.Instance) *Preferences {
 		})
 	})
 
-	currentConfig := controlling.Config()
+	p.built.Renderer.NotifyProperty("selected", func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.Renderer = renderers[p.built.Renderer.Selected()]
+		})
+	})
 
-	resume := controlling.PauseUpdates()
-	defer resume()
+	p.built.PauseWhenIdle.NotifyProperty("active", func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.PauseWhenIdle = p.built.PauseWhenIdle.Active()
+		})
+	})
 
-	defer func() { log.Println(controlling.Config()) }()
+	p.built.FrameRate.ConnectValueChanged(func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.FrameRate = int(p.built.FrameRate.Value())
+		})
+	})
+
+	p.built.Interpolate.NotifyProperty("active", func() {
+		p.update(func(config *catnipgtk.Config) {
+			config.Interpolate = p.built.Interpolate.Active()
+		})
+	})
+
+	p.bindProfiles()
 
+	p.load(controlling.Config())
+	log.Println(controlling.Config())
+
+	return p
+}
+
+// Reload sets every row to the current config, such as after the config was
+// replaced without going through the preferences.
+func (p *Preferences) Reload() {
+	p.load(p.controlling.Config())
+}
+
+// load sets every row to the given config. The config is not changed unless a
+// value has no row, in which case the row's default is used.
+func (p *Preferences) load(currentConfig *catnipgtk.Config) {
+	resume := p.controlling.PauseUpdates()
+	defer resume()
+
+	p.setFileFolder(currentConfig.FileFolder)
+	p.built.FileLoop.SetActive(currentConfig.FileLoop)
 	p.built.Backend.SetSelected(uint(findOr(input.GetAllBackendNames(), currentConfig.Backend, 0)))
+	// The backend may not have changed, in which case the devices have to be
+	// loaded here instead.
+	p.loadDevices(input.Backends[p.built.Backend.Selected()], currentConfig.Device, true)
 	p.built.Monaural.SetActive(currentConfig.ChannelCount == 1)
 	p.built.SampleRate.SetValue(currentConfig.SampleRate)
 	p.built.SampleSize.SetValue(float64(currentConfig.SampleSize))
 	p.built.WindowFunc.SetSelected(uint(findOr(windowFuncs, currentConfig.WindowFunc, 0)))
+	p.built.FrequencyScale.SetSelected(uint(findOr(frequencyScales, currentConfig.FrequencyScale, 0)))
+	p.built.MinFrequency.SetValue(currentConfig.MinFrequency)
+	p.built.MaxFrequency.SetValue(currentConfig.MaxFrequency)
 	p.built.SmoothFactor.SetValue(currentConfig.SmoothingFactor)
+	p.built.MagnitudeScale.SetSelected(uint(findOr(magnitudeScales, currentConfig.MagnitudeScale, 0)))
+	p.setDecibelRowsSensitive(currentConfig.MagnitudeScale == catnipgtk.MagnitudeDecibel)
+	p.built.DecibelFloor.SetValue(currentConfig.DecibelFloor)
+	p.built.DecibelCeiling.SetValue(currentConfig.DecibelCeiling)
+	p.built.Weighting.SetSelected(uint(findOr(weightings, currentConfig.Weighting, 0)))
+	p.built.ScalingWindow.SetValue(currentConfig.ScalingWindow)
+	p.built.PeakThreshold.SetValue(currentConfig.PeakThreshold)
+	p.built.ZeroThreshold.SetValue(float64(currentConfig.ZeroThreshold))
 	p.built.DrawStyle.SetSelected(uint(findOr(drawStyles, currentConfig.DrawStyle, 0)))
+	p.built.ShowAxes.SetActive(currentConfig.ShowAxes)
 	p.built.LineCap.SetSelected(uint(findOr(lineCaps, currentConfig.LineCap, 0)))
 	p.built.LineWidth.SetValue(currentConfig.LineWidth)
 	p.built.GapWidth.SetValue(currentConfig.GapWidth)
+	p.built.WaterfallHistory.SetValue(float64(currentConfig.WaterfallHistory))
+	p.built.WaterfallDirection.SetSelected(uint(findOr(scrollDirections, currentConfig.WaterfallDirection, 0)))
+	p.built.ColorMap.SetSelected(uint(findOr(colorMaps, currentConfig.ColorMap, 0)))
+	p.built.PeakCapThickness.SetValue(currentConfig.PeakCapThickness)
+	p.built.PeakHoldTime.SetValue(currentConfig.PeakHoldTime)
+	p.built.PeakFallRate.SetValue(currentConfig.PeakFallRate)
+	p.built.ColorMode.SetSelected(uint(findOr(colorModes, currentConfig.ColorMode, 0)))
+	p.built.SplitChannelColors.SetActive(currentConfig.SplitChannelColors)
+	p.built.RightColorStops.SetSensitive(currentConfig.SplitChannelColors)
+	p.colorStops.SetGradient(currentConfig.Gradient)
+	p.rightColorStops.SetGradient(currentConfig.RightGradient)
 	p.built.ShowWindowControls.SetActive(currentConfig.WindowControls)
+	p.built.Renderer.SetSelected(uint(findOr(renderers, currentConfig.Renderer, 0)))
+	p.built.PauseWhenIdle.SetActive(currentConfig.PauseWhenIdle)
+	p.built.FrameRate.SetValue(float64(currentConfig.FrameRate))
+	p.built.Interpolate.SetActive(currentConfig.Interpolate)
+}
 
-	return p
+// loadDevices lists the devices of the backend and selects the given one. The
+// devices are then kept current while the preferences are shown. See
+// setDevices for keepMissing.
+func (p *Preferences) loadDevices(backend input.NamedBackend, device string, keepMissing bool) {
+	isFile := backend.Name == fileinput.BackendName
+	p.built.FileFolderRow.SetVisible(isFile)
+	p.built.FileLoopRow.SetVisible(isFile)
+
+	p.devices.SetBackend(backend.Name)
+
+	devices, err := backend.Devices()
+	if err != nil {
+		log.Println("Failed to get devices:", err)
+		return
+	}
+
+	log.Println("Restoring device:", device)
+	p.setDevices(mapSlice(devices, input.Device.String), device, keepMissing)
+}
+
+// setDevices sets the listed devices and selects the given one. If it is not
+// listed, it is still listed as unavailable if keepMissing is true, such as
+// when it is only unplugged. Otherwise, the default device is selected and
+// the config is updated.
+func (p *Preferences) setDevices(devices []string, device string, keepMissing bool) {
+	names := append([]string{""}, devices...)
+	labels := append([]string{"(default)"}, devices...)
+	if keepMissing && findOr(names, device, -1) == -1 {
+		names = append(names, device)
+		labels = append(labels, device+" (unavailable)")
+	}
+
+	// Don't let the model change select another device on the way.
+	p.loadingDevices = true
+	p.deviceNames = names
+	p.built.Device.SetModel(gtk.NewStringList(labels))
+	p.built.Device.SetSelected(uint(findOr(names, device, 0)))
+	p.loadingDevices = false
+
+	if selected := names[p.built.Device.Selected()]; selected != device {
+		p.update(func(config *catnipgtk.Config) {
+			config.Device = selected
+		})
+	}
+}
+
+// setFileFolder sets the folder that the file backend lists devices from. The
+// backend itself is only set up once the visualizer restarts, so it is also
+// done here for the device list.
+func (p *Preferences) setFileFolder(folder string) {
+	fileinput.SetOptions(fileinput.Options{
+		Folder: folder,
+		Loop:   p.controlling.Config().FileLoop,
+	})
+
+	if folder == "" {
+		folder = fileinput.DefaultFolder()
+	}
+	p.built.FileFolderRow.SetSubtitle(folder)
+}
+
+// setDecibelRowsSensitive makes the rows that only apply to one of the
+// magnitude scales sensitive.
+func (p *Preferences) setDecibelRowsSensitive(decibel bool) {
+	p.built.DecibelFloor.SetSensitive(decibel)
+	p.built.DecibelCeiling.SetSensitive(decibel)
+	p.built.Weighting.SetSensitive(decibel)
+	p.built.ScalingWindow.SetSensitive(!decibel)
 }
 
 func (p *Preferences) updateSamplingGroup(config *catnipgtk.Config) {
@@ -223,7 +558,7 @@ func (p *Preferences) updateSamplingGroup(config *catnipgtk.Config) {
 }
 
 func (p *Preferences) save(cfg *catnipgtk.Config) {
-	p.controlling.Config().SaveAsync(func(err error) {
+	p.controlling.Save(func(err error) {
 		if err != nil {
 			log.Println("failed to save preferences:", err)
 			p.PreferencesWindow.AddToast(newErrorToast())
@@ -287,6 +622,46 @@ var windowFuncsModel = gtk.NewStringList([]string{
 	"Blackman",
 })
 
+var frequencyScales = []catnipgtk.FrequencyScale{
+	catnipgtk.FrequencyLinear,
+	catnipgtk.FrequencyLog,
+	catnipgtk.FrequencyMel,
+	catnipgtk.FrequencyOctave3,
+	catnipgtk.FrequencyOctave6,
+	catnipgtk.FrequencyOctave12,
+}
+
+var frequencyScalesModel = gtk.NewStringList([]string{
+	"Linear",
+	"Logarithmic",
+	"Mel",
+	"1/3 Octave Bands",
+	"1/6 Octave Bands",
+	"1/12 Octave Bands",
+})
+
+var magnitudeScales = []catnipgtk.MagnitudeScale{
+	catnipgtk.MagnitudeAuto,
+	catnipgtk.MagnitudeDecibel,
+}
+
+var magnitudeScalesModel = gtk.NewStringList([]string{
+	"Auto",
+	"dBFS",
+})
+
+var weightings = []catnipgtk.Weighting{
+	catnipgtk.WeightingNone,
+	catnipgtk.WeightingA,
+	catnipgtk.WeightingC,
+}
+
+var weightingsModel = gtk.NewStringList([]string{
+	"None",
+	"A-weighting",
+	"C-weighting",
+})
+
 var lineCaps = []cairo.LineCap{
 	cairo.LineCapButt,
 	cairo.LineCapRound,
@@ -302,11 +677,67 @@ var lineCapsModel = gtk.NewStringList([]string{
 var drawStyles = []catnipgtk.DrawStyle{
 	catnipgtk.DrawBottomBars,
 	catnipgtk.DrawLines,
+	catnipgtk.DrawCircle,
+	catnipgtk.DrawWaterfall,
+	catnipgtk.DrawOscilloscope,
 }
 
 var drawStylesModel = gtk.NewStringList([]string{
 	"Bottom Bars",
 	"Lines",
+	"Circle",
+	"Waterfall",
+	"Oscilloscope",
+})
+
+var scrollDirections = []catnipgtk.ScrollDirection{
+	catnipgtk.ScrollDown,
+	catnipgtk.ScrollUp,
+}
+
+var scrollDirectionsModel = gtk.NewStringList([]string{
+	"Down",
+	"Up",
+})
+
+var renderers = []catnipgtk.Renderer{
+	catnipgtk.RendererCairo,
+	catnipgtk.RendererSnapshot,
+}
+
+var renderersModel = gtk.NewStringList([]string{
+	"Cairo",
+	"GSK Snapshot",
+})
+
+var colorModes = []catnipgtk.ColorMode{
+	catnipgtk.ColorTheme,
+	catnipgtk.ColorSolid,
+	catnipgtk.ColorVertical,
+	catnipgtk.ColorHorizontal,
+	catnipgtk.ColorMagnitude,
+}
+
+var colorModesModel = gtk.NewStringList([]string{
+	"Theme",
+	"Solid",
+	"Vertical Gradient",
+	"Horizontal Gradient",
+	"Magnitude",
+})
+
+var colorMaps = []catnipgtk.ColorMap{
+	catnipgtk.ColorMapInferno,
+	catnipgtk.ColorMapMagma,
+	catnipgtk.ColorMapViridis,
+	catnipgtk.ColorMapGrayscale,
+}
+
+var colorMapsModel = gtk.NewStringList([]string{
+	"Inferno",
+	"Magma",
+	"Viridis",
+	"Grayscale",
 })
 
 func newErrorToast() *adw.Toast {
@@ -0,0 +1,134 @@
+package preferences
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+func (p *Preferences) bindProfiles() {
+	// loadingProfiles is true while the profile list is being rebuilt, so
+	// that the selection changes do not switch profiles.
+	var loadingProfiles bool
+
+	loadProfiles := func() {
+		names, err := catnipgtk.ListProfiles()
+		if err != nil {
+			log.Println("cannot list profiles:", err)
+		}
+
+		loadingProfiles = true
+		defer func() { loadingProfiles = false }()
+
+		p.profiles = append([]string{""}, names...)
+		p.built.Profile.SetModel(gtk.NewStringList(append([]string{"Default"}, names...)))
+		p.built.Profile.SetSelected(uint(findOr(p.profiles, p.controlling.Profile(), 0)))
+
+		isDefault := p.controlling.Profile() == ""
+		p.built.RenameProfile.SetSensitive(!isDefault)
+		p.built.DeleteProfile.SetSensitive(!isDefault)
+	}
+
+	p.controlling.ConnectProfileChanged(func(string) {
+		p.load(p.controlling.Config())
+		loadProfiles()
+	})
+
+	p.built.Profile.NotifyProperty("selected", func() {
+		if loadingProfiles {
+			return
+		}
+
+		name := p.profiles[p.built.Profile.Selected()]
+		if name == p.controlling.Profile() {
+			return
+		}
+
+		if err := p.controlling.SwitchProfile(name); err != nil {
+			p.profileError("cannot switch profile", err)
+			loadProfiles()
+		}
+	})
+
+	// profileName returns the name typed into the entry.
+	profileName := func() string {
+		return strings.TrimSpace(p.built.ProfileName.Text())
+	}
+
+	// createProfile creates a profile with the given config and switches to
+	// it.
+	createProfile := func(cfg catnipgtk.Config) {
+		name := profileName()
+		if err := catnipgtk.CreateProfile(name, cfg); err != nil {
+			p.profileError("cannot create profile", err)
+			return
+		}
+
+		p.built.ProfileName.SetText("")
+
+		if err := p.controlling.SwitchProfile(name); err != nil {
+			p.profileError("cannot switch profile", err)
+			loadProfiles()
+		}
+	}
+
+	p.built.NewProfile.ConnectClicked(func() {
+		createProfile(catnipgtk.DefaultConfig())
+	})
+
+	p.built.DuplicateProfile.ConnectClicked(func() {
+		createProfile(*p.controlling.Config())
+	})
+
+	p.built.RenameProfile.ConnectClicked(func() {
+		from := p.controlling.Profile()
+		if from == "" {
+			p.profileError("cannot rename profile", errors.New("the default profile cannot be renamed"))
+			return
+		}
+
+		to := profileName()
+		if err := catnipgtk.RenameProfile(from, to); err != nil {
+			p.profileError("cannot rename profile", err)
+			return
+		}
+
+		p.built.ProfileName.SetText("")
+		p.controlling.SetProfile(to)
+		loadProfiles()
+	})
+
+	p.built.DeleteProfile.ConnectClicked(func() {
+		name := p.controlling.Profile()
+		if name == "" {
+			p.profileError("cannot delete profile", errors.New("the default profile cannot be deleted"))
+			return
+		}
+
+		if err := catnipgtk.DeleteProfile(name); err != nil {
+			p.profileError("cannot delete profile", err)
+			return
+		}
+
+		if err := p.controlling.SwitchProfile(""); err != nil {
+			p.profileError("cannot switch profile", err)
+			p.controlling.SetProfile("")
+			loadProfiles()
+		}
+	})
+
+	loadProfiles()
+}
+
+// profileError logs the error and shows it in a toast.
+func (p *Preferences) profileError(msg string, err error) {
+	log.Printf("%s: %v", msg, err)
+
+	toast := adw.NewToast(strings.TrimPrefix(err.Error(), "catnipgtk: "))
+	toast.SetPriority(adw.ToastPriorityHigh)
+	p.PreferencesWindow.AddToast(toast)
+}
@@ -0,0 +1,136 @@
+package catnipgtk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ProfilesDir is the directory where the named profiles are saved. Each
+// profile is a config file named after the profile.
+var ProfilesDir = filepath.Join(ConfigDir, "profiles")
+
+// ProfilePath returns the path of the config file of the named profile.
+func ProfilePath(name string) string {
+	return filepath.Join(ProfilesDir, name+".json")
+}
+
+// CheckProfileName returns an error if the name cannot be used for a profile.
+func CheckProfileName(name string) error {
+	switch {
+	case strings.TrimSpace(name) == "":
+		return errors.New("catnipgtk: profile name is empty")
+	case strings.HasPrefix(name, "."):
+		return errors.New("catnipgtk: profile name cannot start with a dot")
+	case strings.ContainsAny(name, `/\`):
+		return errors.New("catnipgtk: profile name cannot contain slashes")
+	}
+	return nil
+}
+
+// ListProfiles returns the names of all profiles, sorted.
+func ListProfiles() ([]string, error) {
+	entries, err := os.ReadDir(ProfilesDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("catnipgtk: failed to list profiles: %w", err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".json")
+		if ok && !entry.IsDir() && CheckProfileName(name) == nil {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// RestoreProfile restores the config of the named profile.
+func RestoreProfile(name string) (Config, error) {
+	if err := CheckProfileName(name); err != nil {
+		return Config{}, err
+	}
+	return RestoreConfigFile(ProfilePath(name))
+}
+
+// CreateProfile creates a new profile with the given config. It fails if the
+// profile already exists.
+func CreateProfile(name string, cfg Config) error {
+	if err := CheckProfileName(name); err != nil {
+		return err
+	}
+
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(ProfilesDir, 0755); err != nil {
+		return fmt.Errorf("catnipgtk: failed to create profiles directory: %w", err)
+	}
+
+	f, err := os.OpenFile(ProfilePath(name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("catnipgtk: profile %q already exists", name)
+		}
+		return fmt.Errorf("catnipgtk: failed to create profile: %w", err)
+	}
+
+	_, err = f.Write(b)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("catnipgtk: failed to write profile: %w", err)
+	}
+	return nil
+}
+
+// RenameProfile renames a profile. It fails if the new name is taken.
+func RenameProfile(from, to string) error {
+	if err := CheckProfileName(to); err != nil {
+		return err
+	}
+
+	// A pending save would bring back the old name.
+	if err := DefaultSaver.Flush(); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(ProfilePath(to)); err == nil {
+		return fmt.Errorf("catnipgtk: profile %q already exists", to)
+	}
+
+	if err := os.Rename(ProfilePath(from), ProfilePath(to)); err != nil {
+		return fmt.Errorf("catnipgtk: failed to rename profile: %w", err)
+	}
+	return nil
+}
+
+// DeleteProfile deletes a profile.
+func DeleteProfile(name string) error {
+	if err := CheckProfileName(name); err != nil {
+		return err
+	}
+
+	// A pending save would bring the profile back.
+	if err := DefaultSaver.Flush(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(ProfilePath(name)); err != nil {
+		return fmt.Errorf("catnipgtk: failed to delete profile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package catnipgtk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/core/glib"
+)
+
+// SaveDelay is how long the DefaultSaver waits for more changes before saving.
+const SaveDelay = 500 * time.Millisecond
+
+// DefaultSaver is the Saver used by Config.SaveAsync and Config.SaveAsyncTo.
+var DefaultSaver = NewSaver(SaveDelay)
+
+// Saver saves configs in the background. Saves to the same file are debounced,
+// so that only the last of many quick changes is written. Files are replaced
+// atomically, and the previous file is kept with a .bak suffix if it was
+// valid.
+//
+// A Saver must only be used from the main thread.
+type Saver struct {
+	delay    time.Duration
+	pending  map[string]*pendingSave // by path
+	inflight map[string]int          // number of queued writes by path
+
+	// queued holds the next config to write for each path. Saves that are
+	// queued while an older one is still waiting replace it, so the main
+	// thread never waits for the writer.
+	queuedMu sync.Mutex
+	queued   map[string]saveJob
+	wake     chan struct{}
+	writing  sync.WaitGroup
+}
+
+type pendingSave struct {
+	config Config
+	done   []func(error)
+	timer  glib.SourceHandle
+}
+
+type saveJob struct {
+	config Config
+	done   []func(error)
+}
+
+// NewSaver creates a new Saver that waits for the given delay after the last
+// change before saving.
+func NewSaver(delay time.Duration) *Saver {
+	return &Saver{
+		delay:    delay,
+		pending:  make(map[string]*pendingSave),
+		inflight: make(map[string]int),
+		queued:   make(map[string]saveJob),
+	}
+}
+
+// Save saves the config to the file at path once no more changes are made to
+// it for the delay. done is called on the main thread once the config is
+// written, with the error if it could not be. It may be nil. If the config is
+// saved again before it is written, only the newer config is written, and the
+// done of both saves is called with the result of that write.
+func (s *Saver) Save(path string, cfg Config, done func(error)) {
+	p, ok := s.pending[path]
+	if !ok {
+		p = &pendingSave{}
+		s.pending[path] = p
+	}
+
+	p.config = cfg
+	if done != nil {
+		p.done = append(p.done, done)
+	}
+
+	if p.timer != 0 {
+		glib.SourceRemove(p.timer)
+	}
+	p.timer = glib.TimeoutAdd(uint(s.delay/time.Millisecond), func() {
+		p.timer = 0
+		s.start(path)
+	})
+}
+
+// Pending returns whether a save to the file at path is waiting or being
+// written.
+func (s *Saver) Pending(path string) bool {
+	return s.pending[path] != nil || s.inflight[path] > 0
+}
+
+// start queues the pending save of the file at path to be written by the
+// writer goroutine.
+func (s *Saver) start(path string) {
+	p := s.pending[path]
+	delete(s.pending, path)
+
+	if s.wake == nil {
+		// Writes are done in order by a single goroutine, so that an older
+		// config never replaces a newer one.
+		s.wake = make(chan struct{}, 1)
+		go s.writer()
+	}
+
+	s.queuedMu.Lock()
+	job, ok := s.queued[path]
+	if !ok {
+		s.inflight[path]++
+		s.writing.Add(1)
+	}
+	// The replaced save is done once the newer config is written.
+	s.queued[path] = saveJob{p.config, append(job.done, p.done...)}
+	s.queuedMu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+		// The writer is already awake.
+	}
+}
+
+func (s *Saver) writer() {
+	for range s.wake {
+		for {
+			path, job, ok := s.dequeue()
+			if !ok {
+				break
+			}
+
+			err := writeConfigFile(path, job.config)
+			glib.IdleAdd(func() {
+				if s.inflight[path]--; s.inflight[path] == 0 {
+					delete(s.inflight, path)
+				}
+				for _, done := range job.done {
+					done(err)
+				}
+			})
+			s.writing.Done()
+		}
+	}
+}
+
+// dequeue takes any of the queued saves.
+func (s *Saver) dequeue() (string, saveJob, bool) {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+
+	for path, job := range s.queued {
+		delete(s.queued, path)
+		return path, job, true
+	}
+	return "", saveJob{}, false
+}
+
+// Flush writes all pending saves now and waits for them to finish, such as
+// before quitting. The done callbacks of the saves that were still waiting are
+// called before it returns.
+func (s *Saver) Flush() error {
+	// Let the writer finish first, so that it does not write an older config
+	// after the ones below.
+	s.writing.Wait()
+
+	var errs []error
+	for path, p := range s.pending {
+		if p.timer != 0 {
+			glib.SourceRemove(p.timer)
+		}
+		delete(s.pending, path)
+
+		err := writeConfigFile(path, p.config)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, done := range p.done {
+			done(err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// writeConfigFile writes the config to the file at path atomically. The
+// previous file is kept as path.bak if it is a valid config.
+func writeConfigFile(path string, cfg Config) error {
+	cfg.Version = ConfigVersion
+
+	b, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("catnipgtk: failed to encode config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("catnipgtk: failed to create config directory: %w", err)
+	}
+
+	// Keep the last good config in case the new one turns out to be bad.
+	// Invalid files are not kept, since they would replace a good backup.
+	if old, err := os.ReadFile(path); err == nil && !bytes.Equal(old, b) {
+		if _, invalid, err := parseConfig(old); err == nil && invalid == nil {
+			if err := writeFileAtomic(path+".bak", old); err != nil {
+				return fmt.Errorf("catnipgtk: failed to back up config: %w", err)
+			}
+		}
+	}
+
+	if err := writeFileAtomic(path, b); err != nil {
+		return fmt.Errorf("catnipgtk: failed to save config: %w", err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes a temporary file next to the file at path, then
+// renames it over the file, so that the file is never left half-written.
+func writeFileAtomic(path string, b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name()) // in case of errors
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,78 @@
+package catnipgtk
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+)
+
+func TestSaverCallsEveryDone(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	// The config cannot be saved under a file.
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(file, "config.json")
+
+	s := NewSaver(time.Millisecond)
+
+	var calls []int
+	for i := 0; i < 5; i++ {
+		i := i
+		s.Save(path, DefaultConfig(), func(err error) {
+			if err == nil {
+				t.Error("saving under a file succeeded")
+			}
+			calls = append(calls, i)
+		})
+	}
+
+	ctx := glib.MainContextDefault()
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Pending(path) && time.Now().Before(deadline) {
+		ctx.Iteration(false)
+		time.Sleep(time.Millisecond)
+	}
+
+	// The saves are written once, so every done gets the same error.
+	if len(calls) != 5 {
+		t.Errorf("done callbacks called = %v, want all 5", calls)
+	}
+}
+
+func TestSaverWritesLatestConfig(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	s := NewSaver(0)
+	ctx := glib.MainContextDefault()
+
+	// Queue many writes without waiting for the writer in between.
+	for i := 1; i <= 50; i++ {
+		cfg := DefaultConfig()
+		cfg.ProcessRate = i
+		s.Save(path, cfg, nil)
+		for ctx.Iteration(false) {
+		}
+	}
+
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := RestoreConfigFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.ProcessRate != 50 {
+		t.Errorf("processRate = %d, want 50", cfg.ProcessRate)
+	}
+}
@@ -0,0 +1,215 @@
+package catnipgtk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/noriah/catnip/dsp"
+)
+
+// ConfigVersion is the version of the config file format written by this
+// version of catnip-gtk4. Files without a version are version 0.
+const ConfigVersion = 1
+
+// configMigrations migrate the fields of a config file from the version of
+// their index to the next version. Fields that a file does not have keep
+// their DefaultConfig values, so only renamed or changed fields need to be
+// migrated.
+var configMigrations = []func(fields map[string]json.RawMessage) error{
+	// Version 1 added the version itself. Older files only lack fields.
+	0: func(map[string]json.RawMessage) error { return nil },
+}
+
+// decodeConfig decodes a config file of any version. Missing fields are set to
+// their defaults.
+func decodeConfig(b []byte) (Config, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return Config{}, err
+	}
+
+	var version int
+	if v, ok := fields["version"]; ok {
+		if err := json.Unmarshal(v, &version); err != nil {
+			return Config{}, fmt.Errorf("invalid version: %w", err)
+		}
+	}
+
+	switch {
+	case version < 0:
+		return Config{}, fmt.Errorf("invalid version %d", version)
+	case version > ConfigVersion:
+		// Try anyway. Fields that are unknown to us are dropped once saved.
+		log.Printf("config version %d is newer than %d, some settings may be lost", version, ConfigVersion)
+	}
+
+	for v := version; v < ConfigVersion; v++ {
+		if err := configMigrations[v](fields); err != nil {
+			return Config{}, fmt.Errorf("cannot migrate from version %d: %w", v, err)
+		}
+	}
+
+	b, err := json.Marshal(fields)
+	if err != nil {
+		return Config{}, err
+	}
+
+	config := DefaultConfig()
+	if err := json.Unmarshal(b, &config); err != nil {
+		return Config{}, err
+	}
+	config.Version = ConfigVersion
+
+	return config, nil
+}
+
+// Validate clamps values that are out of range and resets values that are
+// invalid to their defaults. It returns an error listing the invalid values,
+// which is nil if there were none. The config is valid afterwards either way.
+func (c *Config) Validate() error {
+	def := DefaultConfig()
+	var errs []error
+
+	invalid := func(field string, value any) {
+		errs = append(errs, fmt.Errorf("invalid %s %v", field, value))
+	}
+
+	if !(c.SampleRate > 0) {
+		invalid("sampleRate", c.SampleRate)
+		c.SampleRate = def.SampleRate
+	}
+	c.SampleRate = clamp(c.SampleRate, 4000, 192000)
+
+	if c.SampleSize <= 0 {
+		invalid("sampleSize", c.SampleSize)
+		c.SampleSize = def.SampleSize
+	}
+	c.SampleSize = clamp(c.SampleSize, 64, 2048)
+
+	if c.ChannelCount != 1 && c.ChannelCount != 2 {
+		invalid("channelCount", c.ChannelCount)
+		c.ChannelCount = def.ChannelCount
+	}
+
+	c.ProcessRate = max(c.ProcessRate, 0)
+
+	if _, ok := WindowFuncs[c.WindowFunc]; !ok {
+		invalid("windowFunc", fmt.Sprintf("%q", c.WindowFunc))
+		c.WindowFunc = def.WindowFunc
+	}
+
+	c.SmoothingFactor = clamp(c.SmoothingFactor, 0, 1)
+	if c.SmoothingMethod < dsp.SmoothUnspecified || c.SmoothingMethod > dsp.SmoothNewAverage {
+		invalid("smoothingMethod", c.SmoothingMethod)
+		c.SmoothingMethod = def.SmoothingMethod
+	}
+
+	if c.DrawStyle < DrawBottomBars || c.DrawStyle > DrawOscilloscope {
+		invalid("drawStyle", c.DrawStyle)
+		c.DrawStyle = def.DrawStyle
+	}
+
+	switch c.LineCap {
+	case cairo.LineCapButt, cairo.LineCapRound, cairo.LineCapSquare:
+	default:
+		invalid("lineCap", c.LineCap)
+		c.LineCap = def.LineCap
+	}
+
+	c.LineWidth = clamp(c.LineWidth, 0, 25)
+	c.GapWidth = clamp(c.GapWidth, 0, 25)
+
+	checkEnum(&errs, "frequencyScale", &c.FrequencyScale, def.FrequencyScale,
+		FrequencyLinear, FrequencyLog, FrequencyMel, FrequencyOctave3, FrequencyOctave6, FrequencyOctave12)
+	c.MinFrequency = clamp(c.MinFrequency, 1, 96000)
+	c.MaxFrequency = clamp(c.MaxFrequency, 1, 96000)
+	if !checkRange(&errs, "frequency range", &c.MinFrequency, &c.MaxFrequency) {
+		c.MinFrequency = def.MinFrequency
+		c.MaxFrequency = def.MaxFrequency
+	}
+
+	checkEnum(&errs, "magnitudeScale", &c.MagnitudeScale, def.MagnitudeScale,
+		MagnitudeAuto, MagnitudeDecibel)
+	c.DecibelFloor = clamp(c.DecibelFloor, -200, 0)
+	c.DecibelCeiling = clamp(c.DecibelCeiling, -200, 20)
+	if !checkRange(&errs, "decibel range", &c.DecibelFloor, &c.DecibelCeiling) {
+		c.DecibelFloor = def.DecibelFloor
+		c.DecibelCeiling = def.DecibelCeiling
+	}
+	checkEnum(&errs, "weighting", &c.Weighting, def.Weighting,
+		WeightingNone, WeightingA, WeightingC)
+	c.ScalingWindow = clamp(c.ScalingWindow, 0.1, 30)
+	c.PeakThreshold = clamp(c.PeakThreshold, 0, 1)
+	c.ZeroThreshold = clamp(c.ZeroThreshold, 0, 1000)
+
+	c.WaterfallHistory = clamp(c.WaterfallHistory, 16, 2048)
+	checkEnum(&errs, "waterfallDirection", &c.WaterfallDirection, def.WaterfallDirection,
+		ScrollDown, ScrollUp)
+	if _, ok := ColorMaps[c.ColorMap]; !ok {
+		if c.ColorMap != "" {
+			invalid("colorMap", fmt.Sprintf("%q", c.ColorMap))
+		}
+		c.ColorMap = def.ColorMap
+	}
+
+	c.PeakHoldTime = clamp(c.PeakHoldTime, 0, 5)
+	c.PeakFallRate = clamp(c.PeakFallRate, 0, 20)
+	c.PeakCapThickness = clamp(c.PeakCapThickness, 0, 25)
+
+	checkEnum(&errs, "colorMode", &c.ColorMode, def.ColorMode,
+		ColorTheme, ColorSolid, ColorVertical, ColorHorizontal, ColorMagnitude)
+	if c.Gradient.Len() == 0 {
+		c.Gradient = def.Gradient
+	}
+	if c.RightGradient.Len() == 0 {
+		c.RightGradient = def.RightGradient
+	}
+
+	checkEnum(&errs, "renderer", &c.Renderer, def.Renderer,
+		RendererCairo, RendererSnapshot)
+	c.FrameRate = clamp(c.FrameRate, 0, 500)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("catnipgtk: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// checkEnum resets the value to the default if it is not one of the valid
+// values. Empty values are reset without an error.
+func checkEnum[T ~string](errs *[]error, field string, value *T, def T, valid ...T) {
+	for _, v := range valid {
+		if *value == v {
+			return
+		}
+	}
+	if *value != "" {
+		*errs = append(*errs, fmt.Errorf("invalid %s %q", field, *value))
+	}
+	*value = def
+}
+
+// checkRange swaps the limits of a range if they are the wrong way around. It
+// returns false if they are equal, in which case the caller resets them.
+func checkRange(errs *[]error, field string, lo, hi *float64) bool {
+	if *lo < *hi {
+		return true
+	}
+	*errs = append(*errs, fmt.Errorf("invalid %s %v to %v", field, *lo, *hi))
+	if *lo == *hi {
+		return false
+	}
+	*lo, *hi = *hi, *lo
+	return true
+}
+
+func clamp[T ~int | ~float64](v, lo, hi T) T {
+	if math.IsNaN(float64(v)) {
+		return lo
+	}
+	return max(lo, min(v, hi))
+}
@@ -0,0 +1,103 @@
+package catnipgtk
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateRanges(t *testing.T) {
+	def := DefaultConfig()
+
+	tests := []struct {
+		name             string
+		minFreq, maxFreq float64
+		floor, ceiling   float64
+		wantMin          float64
+		wantMax          float64
+		wantFloor        float64
+		wantCeiling      float64
+	}{
+		{"valid", 50, 10000, -80, 0, 50, 10000, -80, 0},
+		{"swapped", 10000, 50, 0, -80, 50, 10000, -80, 0},
+		{"equal", 440, 440, -20, -20, def.MinFrequency, def.MaxFrequency, def.DecibelFloor, def.DecibelCeiling},
+		{"clamped then swapped", 200000, 20, 10, -300, 20, 96000, -200, 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := DefaultConfig()
+			c.MinFrequency = test.minFreq
+			c.MaxFrequency = test.maxFreq
+			c.DecibelFloor = test.floor
+			c.DecibelCeiling = test.ceiling
+
+			err := c.Validate()
+			if (err != nil) != (test.name != "valid") {
+				t.Errorf("Validate() = %v", err)
+			}
+
+			if c.MinFrequency != test.wantMin || c.MaxFrequency != test.wantMax {
+				t.Errorf("frequencies = %v to %v, want %v to %v",
+					c.MinFrequency, c.MaxFrequency, test.wantMin, test.wantMax)
+			}
+			if c.DecibelFloor != test.wantFloor || c.DecibelCeiling != test.wantCeiling {
+				t.Errorf("decibels = %v to %v, want %v to %v",
+					c.DecibelFloor, c.DecibelCeiling, test.wantFloor, test.wantCeiling)
+			}
+		})
+	}
+}
+
+func TestParseConfigRepairs(t *testing.T) {
+	config, invalid, err := parseConfig([]byte(`{
+		"version": 1,
+		"sampleRate": -1,
+		"drawStyle": 99,
+		"lineWidth": 100,
+		"minFrequency": 5000,
+		"maxFrequency": 100
+	}`))
+	if err != nil {
+		t.Fatal("parseConfig failed:", err)
+	}
+	if invalid == nil {
+		t.Error("parseConfig did not report the invalid values")
+	}
+
+	def := DefaultConfig()
+	if config.SampleRate != def.SampleRate {
+		t.Errorf("sampleRate = %v, want %v", config.SampleRate, def.SampleRate)
+	}
+	if config.DrawStyle != def.DrawStyle {
+		t.Errorf("drawStyle = %v, want %v", config.DrawStyle, def.DrawStyle)
+	}
+	if config.LineWidth != 25 {
+		t.Errorf("lineWidth = %v, want 25", config.LineWidth)
+	}
+	if config.MinFrequency != 100 || config.MaxFrequency != 5000 {
+		t.Errorf("frequencies = %v to %v, want 100 to 5000", config.MinFrequency, config.MaxFrequency)
+	}
+
+	if _, _, err := parseConfig([]byte(`{"sampleRate": "fast"}`)); err == nil {
+		t.Error("parseConfig decoded a config of the wrong type")
+	}
+}
+
+func TestReadConfigFileReportsRepairs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "windowFunc": "bogus"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, invalid, err := ReadConfigFile(path)
+	if err != nil {
+		t.Fatal("ReadConfigFile failed:", err)
+	}
+	if invalid == nil {
+		t.Error("ReadConfigFile did not report the unknown window function")
+	}
+	if def := DefaultConfig(); config.WindowFunc != def.WindowFunc {
+		t.Errorf("windowFunc = %q, want %q", config.WindowFunc, def.WindowFunc)
+	}
+}
@@ -0,0 +1,369 @@
+package catnipgtk
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/diamondburned/gotk4/pkg/cairo"
+	"github.com/noriah/catnip/input"
+
+	window "github.com/noriah/catnip/util"
+)
+
+// spectrum holds the state shared by the displays that draw the spectrum. It
+// implements processor.Output and all the settings of Display, but leaves the
+// drawing to the displays.
+type spectrum struct {
+	window    *window.MovingWindow
+	drawStyle DrawStyle
+
+	waterfall struct {
+		history   frameHistory
+		direction ScrollDirection
+		colorMap  ColorMap
+		surface   *cairo.Surface
+		rowHeight int
+		painted   int // number of frames painted onto the surface
+	}
+
+	// ticker draws the display on every frame that has something new to
+	// draw. It is nil if the spectrum is not drawn onto a widget.
+	ticker *frameTicker
+
+	lock sync.Mutex
+	now  func() time.Time // used for the peak caps
+
+	binsBuffer [][]float64 // as drawn
+	interp     frameInterpolator
+	dirty      bool // true if changed since the last frame
+	peaks      peakCaps
+	colors     strokeColors
+	nchannels  int
+	peak       float64
+	scale      float64
+	zeroes     int // number of silent frames in a row
+	scaling    Scaling
+	mapping    FrequencyMapping
+	axes       bool
+	sampleRate float64
+	sampleSize int
+
+	pauseWhenIdle bool
+
+	barWidth   float64
+	spaceWidth float64
+	binWidth   float64
+	lineCap    cairo.LineCap
+
+	width  int
+	height int
+}
+
+func (d *spectrum) init(sampleRate float64, sampleSize int) {
+	d.now = time.Now
+	d.scaling = Scaling{
+		Auto:          true,
+		Window:        ScalingWindow,
+		PeakThreshold: PeakThreshold,
+		ZeroThreshold: ZeroThreshold,
+	}
+	d.mapping = DefaultConfig().FrequencyMapping().Clamp(sampleRate)
+	d.SetSizes(2, 3)
+	d.SetLineCap(cairo.LineCapRound)
+	d.SetDrawStyle(DrawBottomBars)
+	d.SetSamplingParams(sampleRate, sampleSize)
+	d.SetWaterfallParams(DefaultWaterfallHistory, ScrollDown, ColorMapInferno)
+}
+
+// SetSizes sets the sizes of the bars and spaces in the display.
+func (d *spectrum) SetSizes(bar, space float64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.barWidth = bar
+	d.spaceWidth = space
+	d.binWidth = bar + space
+	d.invalidate()
+}
+
+// SetDrawStyle sets the draw style.
+func (d *spectrum) SetDrawStyle(style DrawStyle) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.drawStyle = style
+	d.invalidate()
+}
+
+// SetLineCap sets the line cap.
+func (d *spectrum) SetLineCap(lineCap cairo.LineCap) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.lineCap = lineCap
+	d.invalidate()
+}
+
+// SetPauseWhenIdle sets whether the display stops drawing while the input is
+// silent.
+func (d *spectrum) SetPauseWhenIdle(pause bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.pauseWhenIdle = pause
+	d.invalidate()
+}
+
+// idle returns whether there is nothing new to draw: the input has been silent
+// for ZeroThreshold frames, or long enough to clear the waterfall, and the peak
+// caps have fallen.
+func (d *spectrum) idle() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	return d.idleLocked()
+}
+
+// idleLocked is idle with the lock held.
+func (d *spectrum) idleLocked() bool {
+	if !d.pauseWhenIdle || d.zeroes == 0 || d.interp.blending {
+		return false
+	}
+
+	frames := d.scaling.ZeroThreshold
+	if d.drawStyle == DrawWaterfall {
+		frames = max(frames, d.waterfall.history.len())
+	}
+
+	return d.zeroes >= frames && !d.peaks.visible()
+}
+
+// SetFrameRate sets the most frames drawn per second, or 0 to draw on every
+// frame of the display. It must be called on the main thread.
+func (d *spectrum) SetFrameRate(fps int) {
+	d.ticker.setFrameRate(fps)
+}
+
+// SetInterpolation sets whether the bins are blended between the frames of the
+// analyzer, so that they move smoothly even if the analyzer runs slower than
+// the display is drawn.
+func (d *spectrum) SetInterpolation(interpolate bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.interp.enabled = interpolate
+	d.interp.blending = false
+}
+
+// invalidate makes the display draw the spectrum again on the next frame. The
+// lock must be held.
+func (d *spectrum) invalidate() {
+	d.dirty = true
+	d.ticker.wake()
+}
+
+// frame prepares the bins to be drawn in this frame. It returns whether they
+// or the settings changed since the last frame.
+func (d *spectrum) frame() bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	changed := d.dirty
+	d.dirty = false
+
+	if d.interp.blending {
+		d.interp.blend(d.binsBuffer, d.now())
+		changed = true
+	}
+
+	return changed
+}
+
+// SetSamplingParams sets the sampling rate and size.
+func (d *spectrum) SetSamplingParams(rate float64, size int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.sampleRate = rate
+	d.sampleSize = size
+	d.resetWindow()
+}
+
+// SetScaling sets how the bins are scaled to the height of the display.
+func (d *spectrum) SetScaling(scaling Scaling) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	old := d.scaling
+	d.scaling = scaling
+
+	if old.Window != scaling.Window {
+		d.resetWindow()
+	}
+	d.invalidate()
+}
+
+func (d *spectrum) resetWindow() {
+	windowSize := ((int(d.scaling.Window * d.sampleRate)) / d.sampleSize) * 2
+	d.window = window.NewMovingWindow(max(windowSize, 1))
+}
+
+// SetWaterfallParams sets the number of frames kept by the waterfall, the
+// direction it scrolls in and its color map.
+func (d *spectrum) SetWaterfallParams(history int, direction ScrollDirection, colorMap ColorMap) {
+	if history <= 0 {
+		history = DefaultWaterfallHistory
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.waterfall.history.len() != history {
+		d.waterfall.history.reset(history)
+	}
+	d.waterfall.direction = direction
+	d.waterfall.colorMap = colorMap
+	// Invalidate the surface so everything is repainted.
+	d.waterfall.surface = nil
+	d.invalidate()
+}
+
+// SetPeakCaps sets how long the peak caps are held for, how fast they fall in
+// display heights per second squared and how thick they are. A thickness of 0
+// disables them.
+func (d *spectrum) SetPeakCaps(hold time.Duration, gravity, thickness float64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.peaks.hold = hold
+	d.peaks.gravity = gravity
+	d.peaks.thickness = thickness
+	d.invalidate()
+}
+
+// SetColors sets how the bars and lines are colored.
+func (d *spectrum) SetColors(mode ColorMode, left, right Gradient) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.colors = strokeColors{mode, left, right}
+	d.invalidate()
+}
+
+// AsOutput returns the spectrum as a processor.Output.
+func (d *spectrum) AsOutput() DiscardableOutput {
+	return WrapDiscardableOutput((*spectrumOutput)(d))
+}
+
+type spectrumOutput spectrum
+
+// Write implements processor.Output.
+func (d *spectrumOutput) Write(bins [][]float64, nchannels int) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if len(d.binsBuffer) != len(bins) || len(d.binsBuffer[0]) != len(bins[0]) {
+		d.binsBuffer = input.MakeBuffers(len(bins), len(bins[0]))
+	}
+	if d.interp.enabled {
+		d.interp.push(d.binsBuffer, bins, d.now())
+	} else {
+		input.CopyBuffers(d.binsBuffer, bins)
+	}
+	d.dirty = true
+
+	nbins := (*spectrum)(d).bins(nchannels)
+	var peak float64
+
+	for i := 0; i < nchannels; i++ {
+		for _, val := range bins[i][:nbins] {
+			if val > peak {
+				peak = val
+			}
+		}
+	}
+
+	d.peak = peak
+	d.scale = 1.0
+	d.nchannels = nchannels
+
+	if d.peak >= d.scaling.PeakThreshold {
+		// do some scaling if we are above the PeakThreshold
+		if d.scaling.Auto {
+			vMean, vSD := d.window.Update(d.peak)
+			if t := vMean + (2.0 * vSD); t > 1.0 {
+				d.scale = t
+			}
+		}
+
+		d.zeroes = 0
+	} else if d.zeroes < math.MaxInt32 {
+		d.zeroes++
+	}
+
+	if d.peaks.enabled() {
+		d.peaks.update(bins[:nchannels], nbins, d.scale, d.now())
+	}
+
+	if d.drawStyle == DrawWaterfall {
+		(*spectrum)(d).pushWaterfallFrame(bins, nbins)
+	}
+
+	if !(*spectrum)(d).idleLocked() {
+		d.ticker.wake()
+	}
+
+	return nil
+}
+
+// pushWaterfallFrame adds the given bins into the waterfall history. The first
+// channel is laid out from left to right, and every other channel is mirrored.
+func (d *spectrum) pushWaterfallFrame(bins [][]float64, nbins int) {
+	frame := d.waterfall.history.push(nbins * d.nchannels)
+
+	for ch, chBins := range bins[:d.nchannels] {
+		row := frame[ch*nbins : (ch+1)*nbins]
+		for i, val := range chBins[:nbins] {
+			if ch%2 == 1 {
+				i = nbins - 1 - i
+			}
+			// Normalize the value the same way the bars are scaled.
+			row[i] = min(val/d.scale, 1)
+		}
+	}
+}
+
+// Bins implements processor.Output.
+func (d *spectrumOutput) Bins(nchannels int) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	nbins := (*spectrum)(d).bins(nchannels)
+	if d.peaks.enabled() {
+		// Resize the peaks early so that they are stretched over the new bin
+		// count instead of being reset.
+		d.peaks.resize(nchannels, nbins)
+	}
+
+	return nbins
+}
+
+func (d *spectrum) bins(nchannels int) int {
+	switch d.drawStyle {
+	case DrawCircle:
+		// Fit as many bins as we can around the ring, splitting it between
+		// the channels.
+		circumference := 2 * math.Pi * d.circleRadius(float64(d.width), float64(d.height))
+		return int(circumference/d.binWidth) / max(nchannels, 1)
+	case DrawWaterfall:
+		// Split the width between the channels.
+		return d.width / int(d.binWidth) / max(nchannels, 1)
+	default:
+		return d.width / int(d.binWidth)
+	}
+}
+
+func (d *spectrum) circleRadius(wf, hf float64) float64 {
+	return min(wf, hf) * CircleRadius
+}
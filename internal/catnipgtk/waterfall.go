@@ -0,0 +1,56 @@
+package catnipgtk
+
+// DefaultWaterfallHistory is the number of frames kept by the waterfall if
+// none is given.
+const DefaultWaterfallHistory = 256
+
+// ScrollDirection is the direction that the waterfall scrolls in.
+type ScrollDirection string
+
+const (
+	// ScrollDown draws the newest frame at the top and scrolls the older
+	// frames down.
+	ScrollDown ScrollDirection = "down"
+	// ScrollUp draws the newest frame at the bottom and scrolls the older
+	// frames up.
+	ScrollUp ScrollDirection = "up"
+)
+
+// frameHistory is a ring buffer of past frames.
+type frameHistory struct {
+	frames [][]float64
+	count  int // total number of frames pushed
+}
+
+// reset clears the history and resizes it to hold up to length frames.
+func (h *frameHistory) reset(length int) {
+	h.frames = make([][]float64, length)
+	h.count = 0
+}
+
+// len returns the maximum number of frames that the history can hold.
+func (h *frameHistory) len() int {
+	return len(h.frames)
+}
+
+// push adds a new frame with the given length into the history and returns
+// it for the caller to fill. The returned slice may be reused from an older
+// frame.
+func (h *frameHistory) push(length int) []float64 {
+	ix := h.count % len(h.frames)
+	if cap(h.frames[ix]) < length {
+		h.frames[ix] = make([]float64, length)
+	}
+	h.frames[ix] = h.frames[ix][:length]
+	h.count++
+	return h.frames[ix]
+}
+
+// frame returns the nth frame ever pushed. It returns nil if the frame is no
+// longer in the history.
+func (h *frameHistory) frame(n int) []float64 {
+	if n < 0 || n >= h.count || n < h.count-len(h.frames) {
+		return nil
+	}
+	return h.frames[n%len(h.frames)]
+}
@@ -2,12 +2,21 @@ package catnipgtk
 
 import (
 	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
 	"github.com/diamondburned/gotk4/pkg/gtk/v4"
 )
 
+// toplevelStateSuspended is GDK_TOPLEVEL_STATE_SUSPENDED, which GTK 4.12 and
+// later set when the window cannot be seen at all, such as when it is covered
+// by other windows. It is missing from our bindings.
+const toplevelStateSuspended gdk.ToplevelState = 1 << 16
+
 // Window is the main catnip visualizer window.
 type Window struct {
 	AdwWindow
+	toasts *adw.ToastOverlay
+	status *adw.StatusPage
 }
 
 // AdwWindow is the interface for adwaita's ApplicationWindow.
@@ -32,17 +41,84 @@ func NewWindow(window AdwWindow, display Display) *Window {
 	wrcontrols.SetVAlign(gtk.AlignStart)
 	wrcontrols.SetHAlign(gtk.AlignStart)
 
+	// status is shown over the display when there is nothing to draw. It
+	// does not take any input, so the display can still be right-clicked.
+	status := adw.NewStatusPage()
+	status.SetCanTarget(false)
+	status.SetVisible(false)
+
 	woverlay := gtk.NewOverlay()
+	woverlay.AddOverlay(status)
 	woverlay.AddOverlay(wlcontrols)
 	woverlay.AddOverlay(wrcontrols)
 	woverlay.SetChild(wndh)
 
+	toasts := adw.NewToastOverlay()
+	toasts.SetChild(woverlay)
+
 	window.AddCSSClass("catnip-window")
 	window.SetTitle("Catnip")
 	window.SetDefaultSize(600, 350)
-	window.SetContent(woverlay)
+	window.SetContent(toasts)
+
+	return &Window{window, toasts, status}
+}
+
+// SetStatus shows a status over the display, such as when the input is lost.
+// An empty title hides it.
+func (w *Window) SetStatus(iconName, title, description string) {
+	w.status.SetIconName(iconName)
+	w.status.SetTitle(title)
+	w.status.SetDescription(description)
+	w.status.SetVisible(title != "")
+}
+
+// AddToast shows a toast over the display.
+func (w *Window) AddToast(toast *adw.Toast) {
+	w.toasts.AddToast(toast)
+}
+
+// ConnectVisibilityChanged calls f whenever the window is hidden or shown
+// again. The window is hidden if it is not mapped, is minimized or is
+// suspended. Not every windowing system reports the last two.
+func (w *Window) ConnectVisibilityChanged(f func(visible bool)) {
+	window := w.Window()
+
+	var visible bool
+	update := func(mapped bool, toplevel *gdk.Toplevel) {
+		v := mapped
+		if toplevel != nil {
+			state := toplevel.State()
+			v = v && !state.Has(gdk.ToplevelStateMinimized) && !state.Has(toplevelStateSuspended)
+		}
+		if v != visible {
+			visible = v
+			f(v)
+		}
+	}
+
+	// The surface is created again every time the window is mapped.
+	var toplevel *gdk.Toplevel
+	var stateHandle glib.SignalHandle
+
+	window.ConnectMap(func() {
+		toplevel, _ = glib.BaseObject(window.Surface()).CastType(gdk.GTypeToplevel).(*gdk.Toplevel)
+		if toplevel != nil {
+			surface := toplevel
+			stateHandle = surface.NotifyProperty("state", func() {
+				update(true, surface)
+			})
+		}
+		update(true, toplevel)
+	})
 
-	return &Window{window}
+	window.ConnectUnmap(func() {
+		if toplevel != nil {
+			toplevel.HandlerDisconnect(stateHandle)
+			toplevel = nil
+		}
+		update(false, nil)
+	})
 }
 
 // Window returns the underlying gtk.Window.
@@ -0,0 +1,141 @@
+// Package fileinput provides a catnip input backend that plays audio files
+// from a folder. Each supported file in the folder is a device.
+package fileinput
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/noriah/catnip/input"
+	"libdb.so/catnip-gtk4/internal/audiofile"
+)
+
+// BackendName is the name that the backend is registered with.
+const BackendName = "file"
+
+func init() {
+	input.RegisterBackend(BackendName, Backend{})
+}
+
+// Options are the options of the backend. Since catnip backends are global,
+// so are the options.
+type Options struct {
+	// Folder is the folder to list files from. If it is empty, DefaultFolder
+	// is used.
+	Folder string
+	// Loop plays the file again once it ends instead of ending the session.
+	Loop bool
+}
+
+var options struct {
+	sync.Mutex
+	Options
+}
+
+// SetOptions sets the options of the backend. They apply to devices listed and
+// sessions started afterwards.
+func SetOptions(opts Options) {
+	options.Lock()
+	options.Options = opts
+	options.Unlock()
+}
+
+func currentOptions() Options {
+	options.Lock()
+	defer options.Unlock()
+
+	opts := options.Options
+	if opts.Folder == "" {
+		opts.Folder = DefaultFolder()
+	}
+	return opts
+}
+
+// DefaultFolder returns the folder that is used if Options.Folder is empty,
+// which is ~/Music.
+func DefaultFolder() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "Music"
+	}
+	return filepath.Join(home, "Music")
+}
+
+// Device is an audio file.
+type Device struct {
+	path string
+}
+
+// String returns the name of the file.
+func (d Device) String() string {
+	return filepath.Base(d.path)
+}
+
+// Backend is the file backend. It implements input.Backend.
+type Backend struct{}
+
+var _ input.Backend = Backend{}
+
+// Init implements input.Backend.
+func (Backend) Init() error {
+	return nil
+}
+
+// Close implements input.Backend.
+func (Backend) Close() error {
+	return nil
+}
+
+// Devices returns the supported audio files in the folder, sorted by name.
+func (Backend) Devices() ([]input.Device, error) {
+	folder := currentOptions().Folder
+
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return nil, fmt.Errorf("fileinput: %w", err)
+	}
+
+	var devices []input.Device
+	for _, entry := range entries {
+		path := filepath.Join(folder, entry.Name())
+		if entry.Type().IsRegular() && audiofile.IsSupported(path) {
+			devices = append(devices, Device{path})
+		}
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].String() < devices[j].String()
+	})
+
+	return devices, nil
+}
+
+// DefaultDevice returns the first file in the folder.
+func (b Backend) DefaultDevice() (input.Device, error) {
+	devices, err := b.Devices()
+	if err != nil {
+		return nil, err
+	}
+	if len(devices) == 0 {
+		return nil, fmt.Errorf("fileinput: no audio files in %s", currentOptions().Folder)
+	}
+	return devices[0], nil
+}
+
+// Start implements input.Backend.
+func (Backend) Start(cfg input.SessionConfig) (input.Session, error) {
+	device, ok := cfg.Device.(Device)
+	if !ok {
+		return nil, errors.New("fileinput: device is not a file")
+	}
+
+	return &session{
+		cfg:  cfg,
+		path: device.path,
+		loop: currentOptions().Loop,
+	}, nil
+}
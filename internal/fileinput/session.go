@@ -0,0 +1,173 @@
+package fileinput
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/noriah/catnip/input"
+	"libdb.so/catnip-gtk4/internal/audiofile"
+	"libdb.so/catnip-gtk4/internal/inpututil"
+)
+
+// session plays a file at real-time pace.
+type session struct {
+	cfg  input.SessionConfig
+	path string
+	loop bool
+
+	src *resampler // nil once the file has ended
+}
+
+// Start implements input.Session. Unless the file loops, it returns nil once
+// the file has been played.
+func (s *session) Start(ctx context.Context, dst [][]input.Sample, kickChan chan bool, mu *sync.Mutex) error {
+	if err := s.open(); err != nil {
+		return err
+	}
+	defer func() {
+		if s.src != nil {
+			s.src.file.Close()
+		}
+	}()
+
+	return inpututil.RunRealtime(ctx, s.cfg, dst, kickChan, mu, s.fill)
+}
+
+func (s *session) open() error {
+	file, err := audiofile.Open(s.path)
+	if err != nil {
+		return err
+	}
+
+	s.src = newResampler(file, s.cfg.FrameSize, s.cfg.SampleRate)
+	return nil
+}
+
+// fill fills buf with the next frames of the file. Once the file ends, it is
+// either played again or the rest of buf is filled with silence, and the next
+// call returns io.EOF.
+func (s *session) fill(buf [][]float64) error {
+	var reopened bool
+
+	for filled := 0; filled < len(buf[0]); {
+		if s.src == nil {
+			if filled == 0 {
+				return io.EOF
+			}
+			for _, samples := range buf {
+				for i := filled; i < len(samples); i++ {
+					samples[i] = 0
+				}
+			}
+			return nil
+		}
+
+		rest := make([][]float64, len(buf))
+		for ch := range buf {
+			rest[ch] = buf[ch][filled:]
+		}
+
+		n, err := s.src.Read(rest)
+		filled += n
+
+		if n > 0 {
+			reopened = false
+		}
+
+		switch {
+		case errors.Is(err, io.EOF):
+			s.src.file.Close()
+			s.src = nil
+
+			// Don't keep reopening a file that has no frames.
+			if s.loop && !reopened {
+				if err := s.open(); err != nil {
+					return err
+				}
+				reopened = true
+			}
+		case err != nil:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resampler reads frames from a file at another sample rate using linear
+// interpolation.
+type resampler struct {
+	file *audiofile.File
+	step float64 // file frames per output frame
+	pos  float64 // position between prev and next
+
+	prev []float64
+	next []float64
+
+	in    [][]float64
+	inLen int
+	inPos int
+}
+
+func newResampler(file *audiofile.File, channels int, sampleRate float64) *resampler {
+	return &resampler{
+		file: file,
+		step: file.SampleRate() / sampleRate,
+		pos:  1, // load the first frame on the first read
+		prev: make([]float64, channels),
+		next: make([]float64, channels),
+		in:   input.MakeBuffers(channels, 1024),
+	}
+}
+
+// Read reads up to len(dst[0]) frames into dst. It returns io.EOF once the
+// file has no frames left.
+func (r *resampler) Read(dst [][]float64) (int, error) {
+	if r.step == 1 && r.inPos == r.inLen {
+		return r.file.Read(dst)
+	}
+
+	for i := range dst[0] {
+		for r.pos >= 1 {
+			if err := r.advance(); err != nil {
+				if i > 0 && errors.Is(err, io.EOF) {
+					return i, nil
+				}
+				return i, err
+			}
+			r.pos--
+		}
+
+		for ch := range dst {
+			dst[ch][i] = r.prev[ch] + (r.next[ch]-r.prev[ch])*r.pos
+		}
+		r.pos += r.step
+	}
+
+	return len(dst[0]), nil
+}
+
+func (r *resampler) advance() error {
+	copy(r.prev, r.next)
+
+	if r.inPos == r.inLen {
+		n, err := r.file.Read(r.in)
+		if n == 0 {
+			if err == nil {
+				err = io.EOF
+			}
+			return err
+		}
+		r.inLen = n
+		r.inPos = 0
+	}
+
+	for ch := range r.next {
+		r.next[ch] = r.in[ch][r.inPos]
+	}
+	r.inPos++
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package fileinput
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/noriah/catnip/input"
+)
+
+const testSampleRate = 8000
+
+// writeWAV writes frames of 16-bit mono silence as a WAV file.
+func writeWAV(t *testing.T, path string, frames int) {
+	t.Helper()
+
+	data := make([]byte, 2*frames)
+
+	var header []byte
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(36+len(data)))
+	header = append(header, "WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, 16)
+	header = binary.LittleEndian.AppendUint16(header, 1) // PCM
+	header = binary.LittleEndian.AppendUint16(header, 1) // channels
+	header = binary.LittleEndian.AppendUint32(header, testSampleRate)
+	header = binary.LittleEndian.AppendUint32(header, 2*testSampleRate) // bytes per second
+	header = binary.LittleEndian.AppendUint16(header, 2)                // bytes per frame
+	header = binary.LittleEndian.AppendUint16(header, 16)               // bits per sample
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(data)))
+
+	if err := os.WriteFile(path, append(header, data...), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startSession plays the file at path until it ends or the timeout passes. It
+// returns the number of buffers it played and the error of the session.
+func startSession(t *testing.T, path string, loop bool, timeout time.Duration) (int, error) {
+	t.Helper()
+
+	SetOptions(Options{Folder: filepath.Dir(path), Loop: loop})
+
+	cfg := input.SessionConfig{
+		Device:     Device{path},
+		FrameSize:  1,
+		SampleSize: 64,
+		SampleRate: testSampleRate,
+	}
+
+	session, err := Backend{}.Start(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	kicks := make(chan bool)
+	var buffers int
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for range kicks {
+			buffers++
+		}
+	}()
+
+	err = session.Start(ctx, input.MakeBuffers(cfg.FrameSize, cfg.SampleSize), kicks, &sync.Mutex{})
+	close(kicks)
+	wg.Wait()
+
+	return buffers, err
+}
+
+func TestSessionEnds(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "short.wav")
+	// Two and a half buffers.
+	writeWAV(t, path, 160)
+
+	buffers, err := startSession(t, path, false, 5*time.Second)
+	if err != nil {
+		t.Fatal("session failed:", err)
+	}
+	if buffers != 3 {
+		t.Errorf("played %d buffers, want 3", buffers)
+	}
+}
+
+func TestSessionLoops(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "short.wav")
+	writeWAV(t, path, 160)
+
+	// Three buffers of 8ms each would end the file without looping.
+	buffers, err := startSession(t, path, true, 100*time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("session ended with %v, want it to play until stopped", err)
+	}
+	if buffers <= 3 {
+		t.Errorf("played %d buffers, want more than the file has", buffers)
+	}
+}
+
+func TestSessionEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.wav")
+	writeWAV(t, path, 0)
+
+	for _, loop := range []bool{false, true} {
+		buffers, err := startSession(t, path, loop, 5*time.Second)
+		if err != nil {
+			t.Errorf("loop=%t: session failed: %v", loop, err)
+		}
+		if buffers != 0 {
+			t.Errorf("loop=%t: played %d buffers of an empty file", loop, buffers)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package geninput provides a catnip input backend that generates test
+// signals, so that the visualizer can be tried without any audio hardware.
+// Each kind of signal is a device.
+package geninput
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/noriah/catnip/input"
+	"libdb.so/catnip-gtk4/internal/inpututil"
+)
+
+// BackendName is the name that the backend is registered with.
+const BackendName = "generator"
+
+func init() {
+	input.RegisterBackend(BackendName, Backend{})
+}
+
+// Amplitude is the peak amplitude of the generated signals.
+const Amplitude = 0.5
+
+// Signal is the kind of test signal to generate.
+type Signal string
+
+const (
+	// SignalSweep is a sine wave that sweeps logarithmically from 20 Hz up to
+	// 20 kHz, or the Nyquist frequency if it is lower, every SweepDuration.
+	SignalSweep Signal = "Sine Sweep"
+	// SignalWhiteNoise is white noise, which has equal power per frequency.
+	SignalWhiteNoise Signal = "White Noise"
+	// SignalPinkNoise is pink noise, which has equal power per octave.
+	SignalPinkNoise Signal = "Pink Noise"
+	// SignalChord is an A major chord of sine waves from A3 to A4.
+	SignalChord Signal = "Chord"
+	// SignalImpulse is a single-sample click every ImpulseInterval seconds.
+	SignalImpulse Signal = "Impulse"
+)
+
+// Signals are all the signals that can be generated.
+var Signals = []Signal{
+	SignalSweep,
+	SignalWhiteNoise,
+	SignalPinkNoise,
+	SignalChord,
+	SignalImpulse,
+}
+
+const (
+	// SweepDuration is how long a sine sweep takes in seconds.
+	SweepDuration = 10
+	// ImpulseInterval is the time between impulses in seconds.
+	ImpulseInterval = 0.5
+)
+
+// String implements input.Device.
+func (s Signal) String() string {
+	return string(s)
+}
+
+// Backend is the generator backend. It implements input.Backend.
+type Backend struct{}
+
+var _ input.Backend = Backend{}
+
+// Init implements input.Backend.
+func (Backend) Init() error {
+	return nil
+}
+
+// Close implements input.Backend.
+func (Backend) Close() error {
+	return nil
+}
+
+// Devices returns all Signals.
+func (Backend) Devices() ([]input.Device, error) {
+	devices := make([]input.Device, len(Signals))
+	for i, signal := range Signals {
+		devices[i] = signal
+	}
+	return devices, nil
+}
+
+// DefaultDevice returns SignalSweep.
+func (Backend) DefaultDevice() (input.Device, error) {
+	return SignalSweep, nil
+}
+
+// Start implements input.Backend.
+func (Backend) Start(cfg input.SessionConfig) (input.Session, error) {
+	signal, ok := cfg.Device.(Signal)
+	if !ok {
+		return nil, errors.New("geninput: device is not a signal")
+	}
+
+	s := &session{cfg: cfg}
+	for ch := 0; ch < cfg.FrameSize; ch++ {
+		gen, err := newGenerator(signal, ch, cfg.SampleRate)
+		if err != nil {
+			return nil, err
+		}
+		s.channels = append(s.channels, gen)
+	}
+
+	return s, nil
+}
+
+type session struct {
+	cfg      input.SessionConfig
+	channels []generator
+}
+
+// Start implements input.Session.
+func (s *session) Start(ctx context.Context, dst [][]input.Sample, kickChan chan bool, mu *sync.Mutex) error {
+	return inpututil.RunRealtime(ctx, s.cfg, dst, kickChan, mu, func(buf [][]float64) error {
+		for ch, samples := range buf {
+			for i := range samples {
+				samples[i] = s.channels[ch].next()
+			}
+		}
+		return nil
+	})
+}
+
+// generator generates the samples of a single channel.
+type generator interface {
+	next() float64
+}
+
+func newGenerator(signal Signal, ch int, sampleRate float64) (generator, error) {
+	// Seed each channel differently so that the noise is not correlated, but
+	// the same every time.
+	random := rand.New(rand.NewSource(int64(ch) + 1))
+
+	switch signal {
+	case SignalSweep:
+		return &sweep{
+			rate: sampleRate,
+			from: 20,
+			to:   math.Min(20000, sampleRate/2),
+		}, nil
+	case SignalWhiteNoise:
+		return whiteNoise{random}, nil
+	case SignalPinkNoise:
+		return &pinkNoise{random: random}, nil
+	case SignalChord:
+		return newChord(sampleRate, 220, 277.18, 329.63, 440), nil
+	case SignalImpulse:
+		return &impulse{period: int(ImpulseInterval * sampleRate)}, nil
+	default:
+		return nil, errors.New("geninput: unknown signal " + string(signal))
+	}
+}
+
+type sweep struct {
+	rate     float64
+	from, to float64
+	phase    float64
+	n        int
+}
+
+func (s *sweep) next() float64 {
+	length := int(SweepDuration * s.rate)
+	t := float64(s.n%length) / float64(length)
+	s.n++
+
+	// Accumulate the phase so that the frequency changes smoothly.
+	freq := s.from * math.Pow(s.to/s.from, t)
+	s.phase = math.Mod(s.phase+2*math.Pi*freq/s.rate, 2*math.Pi)
+
+	return Amplitude * math.Sin(s.phase)
+}
+
+type whiteNoise struct {
+	random *rand.Rand
+}
+
+func (w whiteNoise) next() float64 {
+	return Amplitude * (2*w.random.Float64() - 1)
+}
+
+// pinkNoise filters white noise using Paul Kellet's economy filter.
+type pinkNoise struct {
+	random     *rand.Rand
+	b0, b1, b2 float64
+}
+
+func (p *pinkNoise) next() float64 {
+	white := 2*p.random.Float64() - 1
+
+	p.b0 = 0.99765*p.b0 + white*0.0990460
+	p.b1 = 0.96300*p.b1 + white*0.2965164
+	p.b2 = 0.57000*p.b2 + white*1.0526913
+
+	// The filter has a gain of about 3.5.
+	pink := (p.b0 + p.b1 + p.b2 + white*0.1848) / 3.5
+	return Amplitude * math.Max(-1, math.Min(1, pink))
+}
+
+type chord struct {
+	steps  []float64 // phase step of each tone
+	phases []float64
+}
+
+func newChord(sampleRate float64, freqs ...float64) *chord {
+	c := &chord{
+		steps:  make([]float64, len(freqs)),
+		phases: make([]float64, len(freqs)),
+	}
+	for i, freq := range freqs {
+		c.steps[i] = 2 * math.Pi * freq / sampleRate
+	}
+	return c
+}
+
+func (c *chord) next() float64 {
+	var sum float64
+	for i, step := range c.steps {
+		sum += math.Sin(c.phases[i])
+		c.phases[i] = math.Mod(c.phases[i]+step, 2*math.Pi)
+	}
+	return Amplitude * sum / float64(len(c.steps))
+}
+
+type impulse struct {
+	period int
+	n      int
+}
+
+func (p *impulse) next() float64 {
+	var v float64
+	if p.n%p.period == 0 {
+		v = Amplitude
+	}
+	p.n++
+	return v
+}
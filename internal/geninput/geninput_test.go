@@ -0,0 +1,172 @@
+package geninput
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/noriah/catnip/input"
+)
+
+const testSampleRate = 44100
+
+// generate returns the first n samples of the signal on the given channel.
+func generate(t *testing.T, signal Signal, ch, n int) []float64 {
+	t.Helper()
+
+	gen, err := newGenerator(signal, ch, testSampleRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	samples := make([]float64, n)
+	for i := range samples {
+		samples[i] = gen.next()
+	}
+	return samples
+}
+
+func TestSignalAmplitude(t *testing.T) {
+	// The least peak amplitude that each signal must reach in a second.
+	minPeaks := map[Signal]float64{
+		SignalSweep:      0.99 * Amplitude,
+		SignalWhiteNoise: 0.99 * Amplitude,
+		SignalPinkNoise:  0.25 * Amplitude,
+		SignalChord:      0.5 * Amplitude,
+		SignalImpulse:    Amplitude,
+	}
+
+	for _, signal := range Signals {
+		t.Run(string(signal), func(t *testing.T) {
+			var peak float64
+			for _, v := range generate(t, signal, 0, testSampleRate) {
+				if math.IsNaN(v) || math.Abs(v) > Amplitude {
+					t.Fatalf("sample %v is outside of ±%v", v, Amplitude)
+				}
+				peak = math.Max(peak, math.Abs(v))
+			}
+
+			if want, ok := minPeaks[signal]; !ok {
+				t.Fatal("no minimum peak for the signal")
+			} else if peak < want {
+				t.Errorf("peak = %v, want at least %v", peak, want)
+			}
+		})
+	}
+}
+
+func TestSweepCoversDuration(t *testing.T) {
+	// A full sweep never gets stuck, and starts over after SweepDuration.
+	samples := generate(t, SignalSweep, 0, SweepDuration*testSampleRate+1)
+
+	var crossings int
+	for i := 1; i < len(samples); i++ {
+		if samples[i-1] < 0 && samples[i] >= 0 {
+			crossings++
+		}
+	}
+
+	// Sweeping logarithmically from 20 Hz to 20 kHz crosses zero about
+	// (20000-20)/ln(1000) times per second.
+	want := SweepDuration * (20000 - 20) / math.Log(1000)
+	if math.Abs(float64(crossings)-want) > want/100 {
+		t.Errorf("%d zero crossings in a sweep, want about %.0f", crossings, want)
+	}
+}
+
+func TestImpulseSpacing(t *testing.T) {
+	for _, rate := range []float64{8000, 44100, 48000} {
+		gen, err := newGenerator(SignalImpulse, 0, rate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		period := int(ImpulseInterval * rate)
+		for i := 0; i < 4*period; i++ {
+			v := gen.next()
+			if impulse := i%period == 0; impulse != (v != 0) {
+				t.Fatalf("rate %v: sample %d is %v, want an impulse every %d samples", rate, i, v, period)
+			}
+		}
+	}
+}
+
+func TestNoiseChannelsDiffer(t *testing.T) {
+	for _, signal := range []Signal{SignalWhiteNoise, SignalPinkNoise} {
+		left := generate(t, signal, 0, 64)
+		right := generate(t, signal, 1, 64)
+
+		same := true
+		for i := range left {
+			same = same && left[i] == right[i]
+		}
+		if same {
+			t.Errorf("%s: both channels have the same noise", signal)
+		}
+	}
+}
+
+func TestSessionFillsChannels(t *testing.T) {
+	for _, signal := range Signals {
+		for channels := 1; channels <= 2; channels++ {
+			cfg := input.SessionConfig{
+				Device:     signal,
+				FrameSize:  channels,
+				SampleSize: 256,
+				SampleRate: testSampleRate,
+			}
+
+			session, err := Backend{}.Start(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			dst := input.MakeBuffers(cfg.FrameSize, cfg.SampleSize)
+			var mu sync.Mutex
+
+			ctx, cancel := context.WithCancel(context.Background())
+			kicks := make(chan bool)
+			done := make(chan error, 1)
+			go func() { done <- session.Start(ctx, dst, kicks, &mu) }()
+
+			<-kicks
+			cancel()
+			<-done
+
+			mu.Lock()
+			for ch, samples := range dst {
+				var sum float64
+				for _, v := range samples {
+					sum += math.Abs(v)
+				}
+				// None of the signals is silent for a whole buffer.
+				if sum == 0 {
+					t.Errorf("%s with %d channels: channel %d is silent", signal, channels, ch)
+				}
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+func TestUnknownSignal(t *testing.T) {
+	cfg := input.SessionConfig{
+		Device:     Signal("Bogus"),
+		FrameSize:  2,
+		SampleSize: 256,
+		SampleRate: testSampleRate,
+	}
+	if _, err := (Backend{}).Start(cfg); err == nil {
+		t.Error("started a session for an unknown signal")
+	}
+
+	cfg.Device = otherDevice{}
+	if _, err := (Backend{}).Start(cfg); err == nil {
+		t.Error("started a session for a device of another backend")
+	}
+}
+
+type otherDevice struct{}
+
+func (otherDevice) String() string { return "Other" }
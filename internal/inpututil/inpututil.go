@@ -0,0 +1,59 @@
+// Package inpututil contains helpers for the catnip input backends in this
+// module.
+package inpututil
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/noriah/catnip/input"
+)
+
+// FillFunc fills buf with the next frames of audio, one buffer per channel.
+// It returns io.EOF once there is no audio left.
+type FillFunc func(buf [][]float64) error
+
+// RunRealtime implements input.Session.Start for sources that can produce
+// audio faster than real time. It calls fill for every buffer and hands the
+// buffer to the processor once a buffer's worth of audio would have been
+// played. It returns nil once fill returns io.EOF.
+func RunRealtime(ctx context.Context, cfg input.SessionConfig, dst [][]input.Sample, kickChan chan bool, mu *sync.Mutex, fill FillFunc) error {
+	if !input.EnsureBufferLen(cfg, dst) {
+		return errors.New("invalid dst length given")
+	}
+
+	buf := input.MakeBuffers(cfg.FrameSize, cfg.SampleSize)
+
+	ticker := time.NewTicker(time.Duration(
+		float64(cfg.SampleSize) / cfg.SampleRate * float64(time.Second)))
+	defer ticker.Stop()
+
+	for {
+		if err := fill(buf); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		mu.Lock()
+		input.CopyBuffers(dst, buf)
+		mu.Unlock()
+
+		// Signal that we've written to dst.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case kickChan <- true:
+		}
+	}
+}
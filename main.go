@@ -3,9 +3,10 @@ package main
 import (
 	"context"
 	"log"
+	"os"
 
 	"github.com/diamondburned/gotk4-adwaita/pkg/adw"
-	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
 	"github.com/diamondburned/gotkit/app"
 	"github.com/diamondburned/gotkit/components/logui"
 	"github.com/diamondburned/gotkit/gtkutil"
@@ -15,6 +16,8 @@ import (
 	"libdb.so/catnip-gtk4/internal/catnipgtk/preferences"
 
 	_ "github.com/noriah/catnip/input/all"
+	_ "libdb.so/catnip-gtk4/internal/fileinput"
+	_ "libdb.so/catnip-gtk4/internal/geninput"
 )
 
 var _ = cssutil.WriteCSS(`
@@ -24,35 +27,69 @@ var _ = cssutil.WriteCSS(`
 `)
 
 func main() {
-	// Register for libadwaita.
-	app.Hook(func(app *app.Application) { app.ConnectActivate(adw.Init) })
+	if wantsHeadless(os.Args[1:]) {
+		runHeadless(os.Args[1:])
+		return
+	}
+
+	// Register for libadwaita. The application handles its own command line,
+	// so activate is never emitted.
+	app.Hook(func(app *app.Application) { app.ConnectStartup(adw.Init) })
+
+	a := app.NewWithFlags(context.Background(), "so.libdb.catnip-gtk4", "catnip-gtk4", gio.ApplicationHandlesCommandLine)
+	addOptions(a.Application)
 
-	a := app.New(context.Background(), "so.libdb.catnip-gtk4", "catnip-gtk4")
-	a.ConnectActivate(func() { activate(a.Context()) })
+	// The command line of every invocation is handled here, in the primary
+	// instance.
+	var s *session
+	a.ConnectCommandLine(func(cmd *gio.ApplicationCommandLine) int {
+		opts, err := parseOptions(cmd)
+		if err == nil {
+			if s == nil {
+				s, err = activate(a.Context(), opts)
+			} else {
+				err = s.apply(opts)
+			}
+		}
+		if err != nil {
+			log.Println("error:", err)
+			return 1
+		}
+		return 0
+	})
 	a.RunMain()
 }
 
-func activate(ctx context.Context) {
-	config, err := catnipgtk.RestoreConfig()
+func activate(ctx context.Context, opts options) (*session, error) {
+	base, err := opts.loadConfig()
 	if err != nil {
-		log.Println("cannot restore config:", err)
-		log.Println("using default config")
-		config = catnipgtk.DefaultConfig()
+		return nil, err
 	}
 
-	display := catnipgtk.NewCairoDisplay(config.SampleRate, config.SampleSize)
-	gtkutil.BindPopoverMenuAtMouse(display, gtk.PosBottom, [][2]string{
-		{"Preferences", "win.prefs"},
-		{"About", "win.about"},
-		{"Logs", "win.logs"},
-		{"Quit", "win.quit"},
-	})
+	config := base
+	opts.override(&config)
+
+	display := catnipgtk.NewSwitchingDisplay(config.SampleRate, config.SampleSize)
+	catnipgtk.BindInspector(display)
 
 	instance := catnipctl.NewInstance(ctx, config, display)
+	instance.SetProfile(opts.profile)
+	bindMenu(ctx, display, instance)
 	prefs := preferences.NewPreferences(instance)
 
 	a := app.FromContext(ctx)
-	a.ConnectShutdown(func() { instance.Finalize() })
+	a.ConnectShutdown(func() {
+		instance.Finalize()
+		if err := catnipgtk.DefaultSaver.Flush(); err != nil {
+			log.Println("cannot save config:", err)
+		}
+	})
+
+	if conn := a.DBusConnection(); conn != nil {
+		if err := catnipctl.ExportDBus(conn, a.DBusObjectPath(), instance); err != nil {
+			log.Println("cannot export D-Bus interface:", err)
+		}
+	}
 
 	w := catnipgtk.NewWindow(adw.NewApplicationWindow(a.Application), display)
 	gtkutil.BindActionMap(w, map[string]func(){
@@ -62,6 +99,47 @@ func activate(ctx context.Context) {
 		"win.quit":  func() { a.Quit() },
 	})
 
+	instance.ConnectStateChanged(func(state catnipctl.State) {
+		switch state {
+		case catnipctl.StateRunning, catnipctl.StateStopped:
+			w.SetStatus("", "", "")
+			return
+		case catnipctl.StateStarting, catnipctl.StateStopping:
+			// Keep showing the status until the retry works.
+			return
+		}
+
+		var reason string
+		if err := instance.LastError(); err != nil {
+			reason = err.Error()
+		}
+		w.SetStatus("audio-input-microphone-symbolic", "Input lost, retrying…", reason)
+	})
+
+	w.ConnectVisibilityChanged(func(visible bool) {
+		instance.SetHidden(!visible)
+	})
+
+	toastError := func(err error) {
+		log.Println("error:", err)
+		toast := adw.NewToast(err.Error())
+		toast.SetPriority(adw.ToastPriorityHigh)
+		w.AddToast(toast)
+	}
+
+	s := newSession(opts, instance, w.Window(), base, func(err error) {
+		if err != nil {
+			toastError(err)
+		}
+		prefs.Reload()
+	})
+	s.css = watchUserCSS(toastError)
+
+	if opts.fullscreen {
+		w.Window().Fullscreen()
+	}
 	w.Window().Show()
 	instance.Start()
+
+	return s, nil
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"github.com/diamondburned/gotkit/app"
+	"github.com/diamondburned/gotkit/app/locale"
+	"github.com/diamondburned/gotkit/gtkutil"
+	"libdb.so/catnip-gtk4/internal/catnipctl"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// bindMenu binds the popover menu shown when right-clicking the display. The
+// menu is built every time so that it lists the current profiles.
+func bindMenu(ctx context.Context, display gtk.Widgetter, instance *catnipctl.Instance) {
+	// profiles.switch switches to the profile named by its parameter. Its
+	// state is the active profile, so the profiles show up as radio items.
+	switchProfile := gio.NewSimpleActionStateful("switch",
+		glib.NewVariantType("s"), glib.NewVariantString(instance.Profile()))
+	switchProfile.ConnectActivate(func(parameter *glib.Variant) {
+		if err := instance.SwitchProfile(parameter.String()); err != nil {
+			app.Error(ctx, err)
+		}
+	})
+
+	instance.ConnectProfileChanged(func(name string) {
+		switchProfile.SetState(glib.NewVariantString(name))
+	})
+
+	group := gio.NewSimpleActionGroup()
+	group.AddAction(switchProfile)
+	gtk.BaseWidget(display).InsertActionGroup("profiles", group)
+
+	gtkutil.BindRightClickAt(display, func(x, y float64) {
+		// The active profile may have been renamed.
+		switchProfile.SetState(glib.NewVariantString(instance.Profile()))
+
+		menu := gtkutil.CustomMenuItems(
+			gtkutil.MenuItem("Preferences", "win.prefs"),
+			gtkutil.MenuItem("About", "win.about"),
+			gtkutil.MenuItem("Logs", "win.logs"),
+			gtkutil.MenuItem("Quit", "win.quit"),
+		)
+		menu.PrependSubmenu(locale.Get("Profiles"), profileMenu())
+
+		popover := gtk.NewPopoverMenuFromModel(menu)
+		popover.SetAutohide(true)
+		popover.SetCascadePopdown(false)
+		popover.SetSizeRequest(gtkutil.PopoverWidth, -1)
+		popover.SetPosition(gtk.PosBottom)
+		popover.SetParent(display)
+
+		at := gdk.NewRectangle(int(x), int(y), 0, 0)
+		popover.SetPointingTo(&at)
+		gtkutil.PopupFinally(popover)
+	})
+}
+
+// profileMenu returns the submenu that lists the profiles. It is built with
+// gio directly, since gtkutil translates every label, and profile names are
+// chosen by the user.
+func profileMenu() *gio.Menu {
+	profiles, err := catnipgtk.ListProfiles()
+	if err != nil {
+		log.Println("cannot list profiles:", err)
+	}
+
+	menu := gio.NewMenu()
+	menu.Append(locale.Get("Default"), "profiles.switch::")
+	for _, name := range profiles {
+		menu.Append(name, "profiles.switch::"+name)
+	}
+
+	manage := gio.NewMenu()
+	manage.Append(locale.Get("Manage Profiles…"), "win.prefs")
+	menu.AppendSection("", manage)
+
+	return menu
+}
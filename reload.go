@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/diamondburned/gotk4/pkg/gdk/v4"
+	"github.com/diamondburned/gotk4/pkg/gio/v2"
+	"github.com/diamondburned/gotk4/pkg/glib/v2"
+	"github.com/diamondburned/gotk4/pkg/gtk/v4"
+	"libdb.so/catnip-gtk4/internal/catnipgtk"
+)
+
+// reloadDelay is how long to wait after the last change to a file before
+// reloading it. Editors often write a file in several steps.
+const reloadDelay = 200 // ms
+
+// monitorFile calls f once the file at path has been changed, created or
+// replaced. The file does not need to exist yet.
+func monitorFile(path string, f func()) (*gio.FileMonitor, error) {
+	monitor, err := gio.NewFileForPath(path).MonitorFile(context.Background(), gio.FileMonitorNone)
+	if err != nil {
+		return nil, fmt.Errorf("cannot monitor %s: %w", path, err)
+	}
+
+	var timeout glib.SourceHandle
+	m := gio.BaseFileMonitor(monitor)
+	m.ConnectChanged(func(_, _ gio.Filer, event gio.FileMonitorEvent) {
+		switch event {
+		case gio.FileMonitorEventChangesDoneHint, gio.FileMonitorEventCreated:
+		default:
+			return
+		}
+
+		if timeout != 0 {
+			glib.SourceRemove(timeout)
+		}
+		timeout = glib.TimeoutAdd(reloadDelay, func() {
+			timeout = 0
+			f()
+		})
+	})
+
+	return m, nil
+}
+
+// watchConfig reloads the session's config file when it is changed on disk.
+// It is called again whenever the file changes to another one.
+func (s *session) watchConfig() {
+	path := s.path()
+	if path == s.watching {
+		return
+	}
+
+	if s.monitor != nil {
+		s.monitor.Cancel()
+		s.monitor = nil
+	}
+	s.watching = path
+
+	monitor, err := monitorFile(path, s.reload)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	s.monitor = monitor
+}
+
+// reload applies the session's config file using Update, keeping the
+// overrides, then calls onReload. Invalid values are repaired before the
+// config is applied, and passed to onReload. Files that are unchanged since
+// they were last saved are ignored, so the session does not reload its own
+// changes.
+func (s *session) reload() {
+	// The file is about to be replaced with a newer config anyway.
+	if catnipgtk.DefaultSaver.Pending(s.path()) {
+		return
+	}
+
+	base, invalid, err := catnipgtk.ReadConfigFile(s.path())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			s.onReload(fmt.Errorf("invalid %s: %w", filepath.Base(s.path()), err))
+		}
+		// Otherwise, it was deleted or is being replaced. The next save
+		// creates it again.
+		return
+	}
+
+	if sameJSON(base, s.saved) {
+		return
+	}
+
+	log.Println("reloading config from", s.path())
+
+	s.base = base
+	s.saved = base
+
+	cfg := base
+	s.opts.override(&cfg)
+	s.instance.Update(func(c *catnipgtk.Config) { *c = cfg })
+
+	if invalid != nil {
+		s.onReload(fmt.Errorf("repaired %s: %w", filepath.Base(s.path()), invalid))
+		return
+	}
+	s.onReload(nil)
+}
+
+// sameJSON returns whether both configs are saved the same way. Colors lose
+// precision when saved, so the configs themselves cannot be compared.
+func sameJSON(a, b catnipgtk.Config) bool {
+	aJSON, err1 := json.Marshal(a)
+	bJSON, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && bytes.Equal(aJSON, bJSON)
+}
+
+// userCSS is the provider of user.css. It replaces the one that gotkit loads at
+// startup, and is reloaded whenever the file changes.
+type userCSS struct {
+	path     string
+	provider *gtk.CSSProvider
+	monitor  *gio.FileMonitor
+	parseErr error
+}
+
+// watchUserCSS loads user.css and reloads it whenever it is changed on disk.
+// onError is called if the file cannot be read or parsed.
+//
+// gotkit also loads user.css at startup, and its provider cannot be removed.
+// This provider has a higher priority, so gotkit's only shows through for rules
+// that were deleted from the file since catnip-gtk4 was started.
+func watchUserCSS(onError func(error)) *userCSS {
+	css := &userCSS{
+		path:     filepath.Join(catnipgtk.ConfigDir, "user.css"),
+		provider: gtk.NewCSSProvider(),
+	}
+	css.provider.ConnectParsingError(func(section *gtk.CSSSection, err error) {
+		if css.parseErr == nil {
+			css.parseErr = fmt.Errorf("invalid user.css at line %d: %w", section.StartLocation().Lines()+1, err)
+		}
+	})
+
+	// Use a higher priority than gotkit's so that this overrides it.
+	display := gdk.DisplayGetDefault()
+	gtk.StyleContextAddProviderForDisplay(display, css.provider, gtk.STYLE_PROVIDER_PRIORITY_USER+201)
+
+	if err := css.load(); err != nil {
+		onError(err)
+	}
+
+	monitor, err := monitorFile(css.path, func() {
+		log.Println("reloading", css.path)
+		if err := css.load(); err != nil {
+			onError(err)
+		}
+	})
+	if err != nil {
+		log.Println(err)
+	}
+	css.monitor = monitor
+
+	return css
+}
+
+// load loads user.css into the provider. If the file cannot be read or its
+// template cannot be rendered, the provider is left as it is.
+func (c *userCSS) load() error {
+	b, err := os.ReadFile(c.path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("cannot read user.css: %w", err)
+	}
+
+	css, err := templateCSS(string(b))
+	if err != nil {
+		return fmt.Errorf("invalid user.css: %w", err)
+	}
+
+	c.parseErr = nil
+	c.provider.LoadFromData(css)
+
+	return c.parseErr
+}
+
+// templateCSS renders the {$variable} templates in css the same way gotkit
+// does for the CSS it loads.
+func templateCSS(css string) (string, error) {
+	t, err := template.New("user.css").Delims("{$", "}").Parse(css)
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	if err := t.Execute(&out, nil); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}